@@ -3,15 +3,23 @@ package incompletejson
 // ArrayScope handles parsing of JSON arrays
 type ArrayScope struct {
 	BaseScope
-	array []Scope
-	state string // "value" or "comma"
-	scope Scope
+	array  []Scope
+	state  string // "value" or "comma"
+	scope  Scope
+	opened bool
+	closer rune // ']', or ')' for a Python tuple
+
+	// snapshot is extended past the length of the snapshots already returned; only the last
+	// element can still change, and it is replaced in place, or in a copy with WithImmutableSnapshots
+	snapshot    []interface{}
+	lastVersion uint64 // version of the last element when the snapshot was taken
 }
 
 func NewArrayScope() *ArrayScope {
 	return &ArrayScope{
-		array: make([]Scope, 0),
-		state: "value",
+		array:  make([]Scope, 0),
+		state:  "value",
+		closer: ']',
 	}
 }
 
@@ -19,11 +27,17 @@ func (a *ArrayScope) Write(letter rune) bool {
 	if a.finish {
 		return false
 	}
+	a.touch()
 
 	// Ignore first [
-	if len(a.array) == 0 && a.state == "value" && a.scope == nil {
+	if !a.opened {
+		a.opened = true
+		a.ctx().begin(a)
 		if letter == '[' {
 			return true
+		} else if letter == '(' && a.ctx().pythonLiterals {
+			a.closer = ')'
+			return true
 		}
 	}
 
@@ -31,25 +45,18 @@ func (a *ArrayScope) Write(letter rune) bool {
 	switch a.state {
 	case "value":
 		if a.scope == nil {
-			if isWhitespace(letter) {
+			if a.isWhitespace(letter) {
 				return true
-			} else if letter == ']' {
+			} else if letter == a.closer {
+				if len(a.array) > 0 && a.ctx().strict && !a.ctx().trailingCommas {
+					// Trailing comma
+					return a.reject("value")
+				}
 				// Empty array case: []
 				a.finish = true
 				return true
-			} else if letter == '{' {
-				a.scope = NewObjectScope()
-				a.scope.SetAllowUnescapedNewlines(a.allowUnescapedNewlines)
-				a.array = append(a.array, a.scope)
-				return a.scope.Write(letter)
-			} else if letter == '[' {
-				a.scope = NewArrayScope()
-				a.scope.SetAllowUnescapedNewlines(a.allowUnescapedNewlines)
-				a.array = append(a.array, a.scope)
-				return a.scope.Write(letter)
 			} else {
-				a.scope = NewLiteralScope()
-				a.scope.SetAllowUnescapedNewlines(a.allowUnescapedNewlines)
+				a.scope = a.newChildScope(letter, len(a.array))
 				a.array = append(a.array, a.scope)
 				return a.scope.Write(letter)
 			}
@@ -57,16 +64,24 @@ func (a *ArrayScope) Write(letter rune) bool {
 			success := a.scope.Write(letter)
 			if success {
 				if a.scope.IsFinished() {
+					a.ctx().complete(a.scope)
 					a.state = "comma"
 				}
 				return true
 			} else {
 				if a.scope.IsFinished() {
+					// The value ended at a delimiter it did not consume (numbers)
+					a.ctx().complete(a.scope)
 					a.state = "comma"
-					return true
+					return a.Write(letter)
+				} else if a.ctx().strict {
+					// Only lenient mode accepts an unfinished value
+					return false
 				} else if letter == ',' {
+					a.ctx().complete(a.scope)
 					a.scope = nil
-				} else if letter == ']' {
+				} else if letter == a.closer {
+					a.ctx().complete(a.scope)
 					a.finish = true
 					return true
 				}
@@ -74,17 +89,17 @@ func (a *ArrayScope) Write(letter rune) bool {
 			}
 		}
 	case "comma":
-		if isWhitespace(letter) {
+		if a.isWhitespace(letter) {
 			return true
 		} else if letter == ',' {
 			a.state = "value"
 			a.scope = nil
 			return true
-		} else if letter == ']' {
+		} else if letter == a.closer {
 			a.finish = true
 			return true
 		} else {
-			return false
+			return a.reject("',' or '" + string(a.closer) + "'")
 		}
 	}
 
@@ -92,9 +107,30 @@ func (a *ArrayScope) Write(letter rune) bool {
 }
 
 func (a *ArrayScope) GetOrAssume() interface{} {
-	result := make([]interface{}, len(a.array))
-	for i, scope := range a.array {
-		result[i] = scope.GetOrAssume()
+	if a.snapshot != nil && a.isCached() {
+		return a.snapshot[:len(a.snapshot):len(a.snapshot)]
 	}
-	return result
+	snapshot := a.snapshot
+	if snapshot == nil {
+		snapshot = make([]interface{}, 0, len(a.array))
+	}
+
+	// Elements before the last one are never written again
+	if n := len(snapshot); n > 0 && baseScope(a.array[n-1]).version != a.lastVersion {
+		if a.ctx().immutableSnapshots {
+			snapshot = append(make([]interface{}, 0, len(a.array)), snapshot...)
+		}
+		snapshot[n-1] = a.array[n-1].GetOrAssume()
+	}
+	for i := len(snapshot); i < len(a.array); i++ {
+		snapshot = append(snapshot, a.array[i].GetOrAssume())
+	}
+	if n := len(a.array); n > 0 {
+		a.lastVersion = baseScope(a.array[n-1]).version
+	}
+
+	a.snapshot = snapshot
+	a.markCached()
+	// The capacity is clipped so that appending to a returned snapshot never writes into this one
+	return snapshot[:len(snapshot):len(snapshot)]
 }
@@ -0,0 +1,40 @@
+package incompletejson
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+)
+
+// SkippedValue describes an incomplete value that UnmarshalTo left at its zero value
+// because it cannot be converted to the target type yet, such as a partial string where
+// an int is expected or "12.5" headed for an int field.
+type SkippedValue struct {
+	Path Path
+	// Value is the value as GetObjects assumes it
+	Value interface{}
+	// Type is the Go type the value was to be stored in
+	Type reflect.Type
+	// Err is the reason the value cannot be converted
+	Err error
+}
+
+// WithOnSkippedValue sets a function called by UnmarshalTo and ParseAs for every incomplete
+// value that is left at its zero value. Complete values that do not fit the target type still
+// fail the decode.
+func WithOnSkippedValue(fn func(SkippedValue)) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.onSkippedValue = fn
+	}
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// implementsUnmarshaler reports whether values of type t decode themselves
+func implementsUnmarshaler(t reflect.Type) bool {
+	pointer := reflect.PointerTo(t)
+	return pointer.Implements(jsonUnmarshalerType) || pointer.Implements(textUnmarshalerType)
+}
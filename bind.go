@@ -0,0 +1,68 @@
+package incompletejson
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// binding is a Go value that Bind keeps up to date with the document
+type binding struct {
+	target reflect.Value // the value v points to
+	root   Scope         // the document the target was last updated from
+	// decoded records the state of every scope when it was last stored in the target
+	decoded map[Scope]bindState
+	// cursors and elements count the members and elements that are final and stored in the target
+	cursors  map[*ObjectScope]memberCursor
+	elements map[*ArrayScope]int
+}
+
+// bindState is the state of a scope when it was stored in a bound value
+type bindState struct {
+	version  uint64
+	complete bool
+}
+
+// Bind keeps the value pointed to by v up to date with the document. The value is decoded now,
+// like UnmarshalTo, and updated again at the end of every Write and Close. An update only stores
+// the values written since the previous one: other fields are left alone, slices are appended to,
+// and the maps and pointers already in v are reused, so references into v stay valid between chunks.
+// Write and Close return the error of the update, if any. When a new document starts, after Reset or
+// in a stream, v is set to its zero value and filled from the new document. Bind(nil) removes the binding.
+func (p *IncompleteJsonParser) Bind(v interface{}) error {
+	if v == nil {
+		p.binding = nil
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &json.InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	p.binding = &binding{target: rv.Elem()}
+	return p.updateBinding()
+}
+
+// updateBinding stores the values written since the previous update in the bound value
+func (p *IncompleteJsonParser) updateBinding() error {
+	b := p.binding
+	if b == nil || p.scope == nil {
+		return nil
+	}
+	if b.root != p.scope {
+		if b.root != nil {
+			b.target.SetZero()
+		}
+		b.root = p.scope
+		b.decoded = make(map[Scope]bindState)
+		b.cursors = make(map[*ObjectScope]memberCursor)
+		b.elements = make(map[*ArrayScope]int)
+	}
+
+	d := p.newDecoder()
+	d.decoded = b.decoded
+	d.cursors = b.cursors
+	d.elements = b.elements
+	if err := d.value(rootNode(p.scope), b.target, false); err != nil {
+		return err
+	}
+	return d.err
+}
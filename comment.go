@@ -0,0 +1,70 @@
+package incompletejson
+
+// commentState tracks a comment between tokens
+type commentState int
+
+const (
+	commentNone      commentState = iota
+	commentStart                  // after a slash that may open a comment
+	commentLine                   // inside a // comment
+	commentBlock                  // inside a /* */ comment
+	commentBlockStar              // after a star inside a /* */ comment
+)
+
+// writeComment skips comments between tokens and reports whether letter belongs to one.
+// Comments are filtered before the scopes see the input, so a comment cut off at the
+// end of a chunk leaves the snapshot untouched.
+func (p *IncompleteJsonParser) writeComment(letter rune) (bool, error) {
+	switch p.comment {
+	case commentNone:
+		if letter != '/' {
+			return false, nil
+		}
+		if p.inString() {
+			return false, nil
+		}
+		p.comment = commentStart
+		return true, nil
+
+	case commentStart:
+		switch letter {
+		case '/':
+			p.comment = commentLine
+		case '*':
+			p.comment = commentBlock
+		default:
+			p.comment = commentNone
+			err := p.newParseError(letter, nil)
+			err.Expected = "'/' or '*'"
+			return true, err
+		}
+		// A comment separates tokens like whitespace, ending a number or an unquoted key before it
+		if p.finish {
+			return true, nil
+		}
+		return true, p.writeToken(' ')
+
+	case commentLine:
+		if letter == '\n' || letter == '\r' || letter == '\u2028' || letter == '\u2029' {
+			p.comment = commentNone
+		}
+
+	case commentBlock:
+		if letter == '*' {
+			p.comment = commentBlockStar
+		}
+
+	case commentBlockStar:
+		if letter == '/' {
+			p.comment = commentNone
+		} else if letter != '*' {
+			p.comment = commentBlock
+		}
+	}
+	return true, nil
+}
+
+// inComment reports whether the input stopped inside a block comment or after a lone slash
+func (p *IncompleteJsonParser) inComment() bool {
+	return p.comment == commentStart || p.comment == commentBlock || p.comment == commentBlockStar
+}
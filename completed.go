@@ -0,0 +1,107 @@
+package incompletejson
+
+// SnapshotPolicy selects which values GetObjects and UnmarshalTo include
+type SnapshotPolicy int
+
+const (
+	// SnapshotAssumed includes every value, completing partial ones with the most likely guess:
+	// truncated strings, "tr" as true, "12." as 12 and partially typed keys with nil values
+	SnapshotAssumed SnapshotPolicy = iota
+	// SnapshotCompleted includes only values that are final. Open containers are kept,
+	// but the value still being written and a key whose value is not final are left out.
+	SnapshotCompleted
+)
+
+// WithSnapshotPolicy sets which values GetObjects and UnmarshalTo include
+func WithSnapshotPolicy(policy SnapshotPolicy) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.snapshotPolicy = policy
+	}
+}
+
+// GetCompleted returns the parsed document with only the values that are final, regardless
+// of the snapshot policy. Containers that are still open are included without the value that
+// is being written. ErrNoInput is returned until some value is final.
+func (p *IncompleteJsonParser) GetCompleted() (interface{}, error) {
+	if p.scope == nil {
+		return nil, ErrNoInput
+	}
+	if p.finish {
+		return p.scope.GetOrAssume(), nil
+	}
+	if value, ok := completedValue(p.scope); ok {
+		return value, nil
+	}
+	return nil, ErrNoInput
+}
+
+// completedValue returns the final part of a value that is still being written.
+// Values that their container has moved past are final even if they are not
+// finished themselves, as lenient mode accepts "[tr," as [true].
+func completedValue(scope Scope) (interface{}, bool) {
+	switch s := scope.(type) {
+	case *ObjectScope:
+		if s.finish {
+			return s.GetOrAssume(), true
+		}
+		return s.completed(), true
+	case *ArrayScope:
+		if s.finish {
+			return s.GetOrAssume(), true
+		}
+		return s.completed(), true
+	case *LiteralScope:
+		if s.finish {
+			return s.GetOrAssume(), true
+		}
+	}
+	return nil, false
+}
+
+// completed builds the final part of an open object
+func (o *ObjectScope) completed() interface{} {
+	var snapshot objectSnapshot
+	var value interface{}
+	if o.ctx().orderedObjects {
+		ordered := NewOrderedObject()
+		snapshot, value = ordered, ordered
+	} else {
+		object := make(map[string]interface{}, len(o.keys))
+		snapshot, value = mapSnapshot(object), object
+	}
+
+	for _, key := range o.keys {
+		snapshot.Set(key, o.members[key].GetOrAssume())
+	}
+
+	// The member being written, once its key is complete
+	if o.keyScope != nil && o.keyScope.IsFinished() && o.valueScope != nil {
+		if member, ok := completedValue(o.valueScope); ok {
+			key := o.currentKey()
+			if member, ok := o.pendingValue(key, member); ok {
+				snapshot.Set(key, member)
+			}
+		}
+	}
+	return value
+}
+
+// completed builds the final part of an open array
+func (a *ArrayScope) completed() interface{} {
+	elements := a.array
+	var pending Scope
+	if a.state == "value" && a.scope != nil {
+		elements, pending = elements[:len(elements)-1], a.scope
+	}
+
+	array := make([]interface{}, 0, len(a.array))
+	for _, element := range elements {
+		array = append(array, element.GetOrAssume())
+	}
+	if pending != nil {
+		if element, ok := completedValue(pending); ok {
+			array = append(array, element)
+		}
+	}
+	return array
+}
@@ -0,0 +1,698 @@
+package incompletejson
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// decoder stores the values of scopes directly in Go values, following the rules of
+// encoding/json without encoding the snapshot to JSON first. Incomplete values that cannot
+// be converted to their target type yet leave the target untouched and are reported.
+type decoder struct {
+	p         *IncompleteJsonParser
+	completed bool // values that are not final are left out, as with SnapshotCompleted
+	// err is the first complete value that does not fit its target type; like
+	// encoding/json, the rest of the document is still decoded
+	err error
+	// decoded is set when updating a bound value: scopes stored since they last
+	// changed are skipped, and maps are updated in place
+	decoded map[Scope]bindState
+	// cursors and elements count the members and elements that a bound value holds for good
+	cursors  map[*ObjectScope]memberCursor
+	elements map[*ArrayScope]int
+	// structType is the struct whose field is being decoded, named by type errors
+	structType reflect.Type
+	// rebuilding is set while a bound value fills a new map or slice, which must
+	// receive every member and element, including those stored elsewhere before
+	rebuilding bool
+}
+
+var (
+	numberType         = reflect.TypeOf(json.Number(""))
+	mapInterfaceType   = reflect.TypeOf(map[string]interface{}(nil))
+	sliceInterfaceType = reflect.TypeOf([]interface{}(nil))
+)
+
+// decodeNode stores the value of a node in the value pointed to by v
+func (p *IncompleteJsonParser) decodeNode(node queryNode, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &json.InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	d := p.newDecoder()
+	if err := d.value(node, rv.Elem(), false); err != nil {
+		return err
+	}
+	return d.err
+}
+
+func (p *IncompleteJsonParser) newDecoder() *decoder {
+	return &decoder{
+		p:         p,
+		completed: p.snapshotPolicy == SnapshotCompleted,
+	}
+}
+
+// value stores the value of a node in v. quoted tells that the value is decoded with the ",string" option.
+func (d *decoder) value(node queryNode, v reflect.Value, quoted bool) error {
+	if d.decoded == nil {
+		return d.store(node, v, quoted)
+	}
+	if d.stored(node) {
+		return nil
+	}
+	if err := d.store(node, v, quoted); err != nil {
+		return err
+	}
+	d.decoded[node.scope] = bindState{version: baseScope(node.scope).version, complete: node.complete}
+	return nil
+}
+
+// stored reports whether a bound value already holds the current state of a node
+func (d *decoder) stored(node queryNode) bool {
+	if d.decoded == nil || d.rebuilding {
+		return false
+	}
+	last, ok := d.decoded[node.scope]
+	return ok && last == bindState{version: baseScope(node.scope).version, complete: node.complete}
+}
+
+// store stores the value of a node in v, leaving v untouched if it is incomplete and cannot be converted yet.
+// When updating a bound value, v is set to its zero value instead, so that it never keeps an earlier guess.
+func (d *decoder) store(node queryNode, v reflect.Value, quoted bool) error {
+	_, isLiteral := node.scope.(*LiteralScope)
+	if !node.complete && (isLiteral || decodesItself(v.Type())) {
+		if d.completed {
+			return nil
+		}
+		// Decode into a new value, so that a value that cannot be converted yet leaves v untouched
+		tmp := reflect.New(v.Type()).Elem()
+		strict := &decoder{p: d.p, completed: d.completed, structType: d.structType}
+		err := strict.valueOf(node, tmp, quoted)
+		if err == nil {
+			err = strict.err
+		}
+		if err != nil {
+			if d.decoded != nil {
+				v.SetZero()
+			}
+			d.skip(node, v.Type(), err)
+			return nil
+		}
+		v.Set(tmp)
+		return nil
+	}
+
+	err := d.valueOf(node, v, quoted)
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok && isLiteral {
+		// A value of the wrong type does not stop the decode
+		if d.decoded != nil {
+			v.SetZero()
+		}
+		if d.err == nil {
+			d.err = typeErr
+		}
+		return nil
+	}
+	return err
+}
+
+func (d *decoder) valueOf(node queryNode, v reflect.Value, quoted bool) error {
+	switch s := node.scope.(type) {
+	case *ObjectScope:
+		return d.object(node, s, v)
+	case *ArrayScope:
+		return d.array(node, s, v)
+	case *LiteralScope:
+		if quoted {
+			return d.quoted(node, s, v)
+		}
+		return d.literal(node, s, v)
+	}
+	return nil
+}
+
+// object stores an object in a struct, a map or an empty interface
+func (d *decoder) object(node queryNode, o *ObjectScope, v reflect.Value) error {
+	u, ut, v := indirect(v, false)
+	if u != nil {
+		return d.unmarshalJSON(node, u)
+	}
+	if ut != nil {
+		return d.mismatch(node, "object", v.Type())
+	}
+
+	var fields *structFields
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return d.mismatch(node, "object", v.Type())
+		}
+		if d.decoded == nil {
+			v.Set(reflect.ValueOf(d.interfaceValue(node)))
+			return nil
+		}
+		// Update the map of a bound value in place
+		if _, seen := d.decoded[node.scope]; !seen || v.IsNil() || v.Elem().Type() != mapInterfaceType {
+			v.Set(reflect.MakeMap(mapInterfaceType))
+			defer func(rebuilding bool) { d.rebuilding = rebuilding }(d.rebuilding)
+			d.rebuilding = true
+		}
+		v = v.Elem()
+	case reflect.Map:
+		if !isMapKey(v.Type().Key()) {
+			return d.mismatch(node, "object", v.Type())
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	case reflect.Struct:
+		fields = cachedFields(v.Type())
+		defer func(structType reflect.Type) { d.structType = structType }(d.structType)
+		d.structType = v.Type()
+	default:
+		return d.mismatch(node, "object", v.Type())
+	}
+
+	children, cursor := d.members(node, o)
+	for _, child := range children {
+		if d.leftOut(child) || d.stored(child) {
+			continue
+		}
+		key := child.path[len(child.path)-1].(string)
+		if fields == nil {
+			keyValue, err := d.mapKey(child, key, v.Type().Key())
+			if err != nil {
+				return err
+			}
+			if !keyValue.IsValid() {
+				continue
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if existing := v.MapIndex(keyValue); d.decoded != nil && existing.IsValid() {
+				elem.Set(existing)
+			}
+			if err := d.value(child, elem, false); err != nil {
+				return err
+			}
+			v.SetMapIndex(keyValue, elem)
+			continue
+		}
+
+		field := fields.lookup(key)
+		if field == nil {
+			continue
+		}
+		fieldValue, err := fieldByIndex(v, field.index)
+		if err != nil {
+			// Like encoding/json, the rest of the object is still decoded
+			if d.err == nil {
+				d.err = err
+			}
+			continue
+		}
+		if err := d.value(child, fieldValue, field.quoted); err != nil {
+			return err
+		}
+	}
+	if d.cursors != nil {
+		d.cursors[o] = cursor
+	}
+	return nil
+}
+
+// members returns the members of an object to store. When updating a bound value, members
+// committed before the previous update are left out, as the value already holds them.
+func (d *decoder) members(node queryNode, o *ObjectScope) ([]queryNode, memberCursor) {
+	if d.cursors == nil || d.rebuilding {
+		return node.children(), o.cursor()
+	}
+	return node.membersSince(d.cursors[o]), o.cursor()
+}
+
+// array stores an array in a slice, an array or an empty interface
+func (d *decoder) array(node queryNode, a *ArrayScope, v reflect.Value) error {
+	u, ut, v := indirect(v, false)
+	if u != nil {
+		return d.unmarshalJSON(node, u)
+	}
+	if ut != nil {
+		return d.mismatch(node, "array", v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return d.mismatch(node, "array", v.Type())
+		}
+		if d.decoded == nil {
+			v.Set(reflect.ValueOf(d.interfaceValue(node)))
+			return nil
+		}
+		// Append to the slice of a bound value, reusing the maps and slices of its elements
+		slice := reflect.New(sliceInterfaceType).Elem()
+		if _, seen := d.decoded[node.scope]; seen && !v.IsNil() && v.Elem().Type() == sliceInterfaceType {
+			slice.Set(v.Elem())
+		} else {
+			defer func(rebuilding bool) { d.rebuilding = rebuilding }(d.rebuilding)
+			d.rebuilding = true
+		}
+		err := d.array(node, a, slice)
+		v.Set(slice)
+		return err
+	case reflect.Array, reflect.Slice:
+	default:
+		return d.mismatch(node, "array", v.Type())
+	}
+
+	// A bound value already holds the elements the array had moved past at the previous update
+	i := 0
+	if d.elements != nil && !d.rebuilding {
+		i = d.elements[a]
+		if v.Kind() == reflect.Slice {
+			i = min(i, v.Len())
+		}
+	}
+	pending := a.pendingScope()
+	for index := i; index < len(a.array); index++ {
+		element := a.array[index]
+		child := node.child(element, index, element != pending)
+		if d.leftOut(child) {
+			continue
+		}
+		if v.Kind() == reflect.Slice && i >= v.Len() {
+			if i >= v.Cap() {
+				v.Grow(1)
+			}
+			v.SetLen(i + 1)
+			v.Index(i).SetZero()
+		}
+		if i < v.Len() {
+			if err := d.value(child, v.Index(i), false); err != nil {
+				return err
+			}
+		}
+		i++
+	}
+
+	if i < v.Len() {
+		if v.Kind() == reflect.Array {
+			for ; i < v.Len(); i++ {
+				v.Index(i).SetZero()
+			}
+		} else {
+			v.SetLen(i)
+		}
+	}
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	if d.elements != nil {
+		moved := len(a.array)
+		if pending != nil {
+			moved--
+		}
+		d.elements[a] = moved
+	}
+	return nil
+}
+
+// literal stores a string, number, bool or null
+func (d *decoder) literal(node queryNode, l *LiteralScope, v reflect.Value) error {
+	kind := l.nodeKind()
+	u, ut, v := indirect(v, kind == NodeNull)
+	if u != nil {
+		return d.unmarshalJSON(node, u)
+	}
+	if ut != nil && kind != NodeString {
+		// Like encoding/json, only strings are passed to UnmarshalText
+		value := "number"
+		if kind == NodeBool {
+			value = "bool"
+		}
+		return d.typeError(node, value, reflect.TypeOf(ut).Elem())
+	}
+
+	switch kind {
+	case NodeNull:
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			v.SetZero()
+		}
+		return nil
+
+	case NodeBool:
+		value := l.GetOrAssume().(bool)
+		switch {
+		case v.Kind() == reflect.Bool:
+			v.SetBool(value)
+		case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+			v.Set(reflect.ValueOf(value))
+		default:
+			return d.typeError(node, "bool", v.Type())
+		}
+		return nil
+
+	case NodeString:
+		text := l.text()
+		if ut != nil {
+			return ut.UnmarshalText([]byte(text))
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			if v.Type() == numberType && !isNumberLexeme(text) {
+				return fmt.Errorf("json: invalid number literal, trying to unmarshal %q into Number", text)
+			}
+			v.SetString(text)
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			data, err := base64.StdEncoding.DecodeString(text)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(data)
+		case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+			v.Set(reflect.ValueOf(text))
+		default:
+			return d.typeError(node, "string", v.Type())
+		}
+		return nil
+	}
+
+	if l.kind != literalNumber {
+		// Infinity and NaN only fit floating-point numbers
+		value := l.GetOrAssume().(float64)
+		switch {
+		case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+			v.SetFloat(value)
+		case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+			v.Set(reflect.ValueOf(value))
+		default:
+			return d.typeError(node, "number", v.Type())
+		}
+		return nil
+	}
+	return d.number(node, l.numberLexeme(), v)
+}
+
+// number stores a number given as its lexeme
+func (d *decoder) number(node queryNode, lexeme string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return d.typeError(node, "number", v.Type())
+		}
+		// Empty interfaces receive the number as the number mode represents it
+		value := decodeNumber(lexeme, &d.p.scopeContext)
+		if isOverflow(value, &d.p.scopeContext) {
+			return d.typeError(node, "number "+lexeme, reflect.TypeOf(value))
+		}
+		if value == nil {
+			return d.typeError(node, "number "+lexeme, v.Type())
+		}
+		v.Set(reflect.ValueOf(value))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(lexeme, 10, 64)
+		if err != nil || v.OverflowInt(value) {
+			return d.typeError(node, "number "+lexeme, v.Type())
+		}
+		v.SetInt(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		value, err := strconv.ParseUint(lexeme, 10, 64)
+		if err != nil || v.OverflowUint(value) {
+			return d.typeError(node, "number "+lexeme, v.Type())
+		}
+		v.SetUint(value)
+	case reflect.Float32, reflect.Float64:
+		value, err := strconv.ParseFloat(lexeme, v.Type().Bits())
+		if err != nil || v.OverflowFloat(value) {
+			return d.typeError(node, "number "+lexeme, v.Type())
+		}
+		v.SetFloat(value)
+	case reflect.String:
+		if v.Type() != numberType {
+			return d.typeError(node, "number", v.Type())
+		}
+		v.SetString(lexeme)
+	default:
+		return d.typeError(node, "number", v.Type())
+	}
+	return nil
+}
+
+// quoted stores a value encoded inside a string by the ",string" option
+func (d *decoder) quoted(node queryNode, l *LiteralScope, v reflect.Value) error {
+	switch l.nodeKind() {
+	case NodeNull:
+		return nil
+	case NodeString:
+	default:
+		return fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal unquoted value into %v", v.Type())
+	}
+
+	text := l.text()
+	_, _, v = indirect(v, text == "null")
+	switch {
+	case text == "null":
+		return nil
+	case v.Kind() == reflect.String && strings.HasPrefix(text, `"`):
+		var value string
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return err
+		}
+		v.SetString(value)
+		return nil
+	case v.Kind() == reflect.Bool && (text == "true" || text == "false"):
+		v.SetBool(text == "true")
+		return nil
+	case v.Kind() != reflect.String && v.Kind() != reflect.Bool && isNumberLexeme(text):
+		return d.number(node, text, v)
+	}
+	return fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", text, v.Type())
+}
+
+// unmarshalJSON passes a value to its own UnmarshalJSON method
+func (d *decoder) unmarshalJSON(node queryNode, u json.Unmarshaler) error {
+	var data []byte
+	var err error
+	if literal, ok := node.scope.(*LiteralScope); ok && literal.kind == literalNumber {
+		data = []byte(literal.numberLexeme())
+	} else {
+		data, err = marshalSnapshot(d.interfaceValue(node))
+		if err != nil {
+			return err
+		}
+	}
+	return u.UnmarshalJSON(data)
+}
+
+// interfaceValue returns a value the way encoding/json decodes it into an empty interface
+func (d *decoder) interfaceValue(node queryNode) interface{} {
+	switch s := node.scope.(type) {
+	case *ObjectScope:
+		object := make(map[string]interface{}, len(s.keys)+1)
+		for _, child := range node.children() {
+			if !d.leftOut(child) {
+				object[child.path[len(child.path)-1].(string)] = d.interfaceValue(child)
+			}
+		}
+		return object
+	case *ArrayScope:
+		children := node.children()
+		array := make([]interface{}, 0, len(children))
+		for _, child := range children {
+			if !d.leftOut(child) {
+				array = append(array, d.interfaceValue(child))
+			}
+		}
+		return array
+	case *LiteralScope:
+		switch {
+		case s.kind != literalNumber:
+			if s.nodeKind() == NodeString {
+				return s.text()
+			}
+			return s.GetOrAssume()
+		}
+		lexeme := s.numberLexeme()
+		value := decodeNumber(lexeme, &d.p.scopeContext)
+		if isOverflow(value, &d.p.scopeContext) {
+			// Like encoding/json, the number is left out and the decode fails once it is complete
+			d.mismatch(node, "number "+lexeme, reflect.TypeOf(value))
+			return nil
+		}
+		return value
+	}
+	return nil
+}
+
+// mapKey converts an object key to the key type of a map
+func (d *decoder) mapKey(node queryNode, key string, t reflect.Type) (reflect.Value, error) {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		keyValue := reflect.New(t)
+		if err := keyValue.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+			return reflect.Value{}, err
+		}
+		return keyValue.Elem(), nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(key).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(key, 10, 64)
+		if err != nil || reflect.Zero(t).OverflowInt(value) {
+			return reflect.Value{}, d.mismatch(node, "number "+key, t)
+		}
+		return reflect.ValueOf(value).Convert(t), nil
+	default:
+		value, err := strconv.ParseUint(key, 10, 64)
+		if err != nil || reflect.Zero(t).OverflowUint(value) {
+			return reflect.Value{}, d.mismatch(node, "number "+key, t)
+		}
+		return reflect.ValueOf(value).Convert(t), nil
+	}
+}
+
+// leftOut reports whether a value is left out because only final values are decoded
+func (d *decoder) leftOut(node queryNode) bool {
+	if !d.completed || node.complete {
+		return false
+	}
+	_, isLiteral := node.scope.(*LiteralScope)
+	return isLiteral
+}
+
+// mismatch handles a container that does not fit its target type: an incomplete one is
+// skipped, and a complete one fails the decode once the rest is decoded
+func (d *decoder) mismatch(node queryNode, value string, t reflect.Type) error {
+	err := d.typeError(node, value, t)
+	if !node.complete {
+		d.skip(node, t, err)
+	} else if d.err == nil {
+		d.err = err
+	}
+	return nil
+}
+
+// skip reports an incomplete value that is left out of the decode
+func (d *decoder) skip(node queryNode, t reflect.Type, err error) {
+	if d.p.onSkippedValue != nil {
+		d.p.onSkippedValue(SkippedValue{Path: node.path, Value: node.scope.GetOrAssume(), Type: t, Err: err})
+	}
+}
+
+// typeError describes a value that does not fit its target type, naming the struct
+// and the field it was headed for like encoding/json
+func (d *decoder) typeError(node queryNode, value string, t reflect.Type) *json.UnmarshalTypeError {
+	field := make([]string, 0, len(node.path))
+	for _, element := range node.path {
+		field = append(field, fmt.Sprint(element))
+	}
+	err := &json.UnmarshalTypeError{Value: value, Type: t, Field: strings.Join(field, ".")}
+	if d.structType != nil {
+		err.Struct = d.structType.Name()
+	}
+	return err
+}
+
+// indirect walks down v, allocating pointers as needed, until it reaches a non-pointer or a
+// value that decodes itself, like the function of the same name in encoding/json. If
+// decodingNull is true, it stops at the last pointer so that it can be set to nil.
+func indirect(v reflect.Value, decodingNull bool) (json.Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
+	if v.Kind() != reflect.Ptr && v.Type().Name() != "" && v.CanAddr() {
+		// Methods with pointer receivers are found through the address
+		v = v.Addr()
+	}
+	for {
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			// Decode into the value the interface points to
+			e := v.Elem()
+			if e.Kind() == reflect.Ptr && !e.IsNil() && (!decodingNull || e.Elem().Kind() == reflect.Ptr) {
+				v = e
+				continue
+			}
+		}
+		if v.Kind() != reflect.Ptr {
+			break
+		}
+		if decodingNull && v.CanSet() {
+			break
+		}
+		if v.Elem().Kind() == reflect.Interface && v.Elem().Elem() == v {
+			// A pointer to an interface holding the pointer itself
+			v = v.Elem()
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if v.Type().NumMethod() > 0 && v.CanInterface() {
+			if u, ok := v.Interface().(json.Unmarshaler); ok {
+				return u, nil, reflect.Value{}
+			}
+			if !decodingNull {
+				if u, ok := v.Interface().(encoding.TextUnmarshaler); ok {
+					return nil, u, reflect.Value{}
+				}
+			}
+		}
+		v = v.Elem()
+	}
+	return nil, nil, v
+}
+
+// fieldByIndex returns a struct field, allocating the embedded structs it is promoted through
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("json: cannot set embedded pointer to unexported struct: %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// decodesItself reports whether values of type t, or of the type t points to, have their own decoding
+func decodesItself(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return implementsUnmarshaler(t)
+}
+
+// isMapKey reports whether encoding/json can decode object keys into type t
+func isMapKey(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// isNumberLexeme reports whether s is a complete number in the JSON grammar
+func isNumberLexeme(s string) bool {
+	state := numberSign
+	for i, r := range s {
+		next, ok := nextNumberState(state, r, i == 0, false)
+		if !ok {
+			return false
+		}
+		state = next
+	}
+	switch state {
+	case numberZero, numberInteger, numberFraction, numberExponent:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,300 @@
+package incompletejson
+
+import "reflect"
+
+// PatchOperation is a JSON Patch (RFC 6902) operation. Besides the standard "add", "replace"
+// and "remove" operations, Diff uses "append" to add text to the end of the string at Path.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON encodes the operation, leaving out the value of "remove" operations
+func (op PatchOperation) MarshalJSON() ([]byte, error) {
+	buf := append([]byte(`{"op":`), quoteJSON(op.Op)...)
+	buf = append(append(buf, `,"path":`...), quoteJSON(op.Path)...)
+	if op.Op != "remove" {
+		var err error
+		buf = append(buf, `,"value":`...)
+		if buf, err = appendSnapshot(buf, op.Value); err != nil {
+			return nil, err
+		}
+	}
+	return append(buf, '}'), nil
+}
+
+// Patch is the set of operations that turns the previous snapshot into the current one
+type Patch struct {
+	// Seq numbers the patches that contain operations, starting at 1. A client that
+	// receives a sequence number that is not one more than the last it applied has missed a patch.
+	Seq        uint64           `json:"seq"`
+	Operations []PatchOperation `json:"ops"`
+}
+
+// patchShadow records the value a client holds after applying the previous patches
+type patchShadow struct {
+	scope   Scope // the scope the value came from, nil for the null of a member with no value yet
+	version uint64
+	kind    NodeKind
+	value   interface{}             // the value of a literal, or the values a collecting member shows
+	keys    []string                // the keys of an object in order
+	members map[string]*patchShadow // the members of an object
+	items   []*patchShadow          // the elements of an array
+	// collecting is set for a member that shows its values gathered by DuplicateKeysCollect
+	// together with the value of a repeated key that is still being written
+	collecting bool
+
+	// cursor, pendingKey and hasPendingKey record the commits of an object seen so far and
+	// the incomplete member shown, so that only members that may have changed are compared
+	cursor        memberCursor
+	pendingKey    string
+	hasPendingKey bool
+}
+
+// Diff returns the JSON Patch operations that describe what changed since the previous call,
+// or since the beginning of the document on the first call. Only values written since the
+// previous call are compared. Strings that grew are reported with "append" operations.
+// When nothing changed, the patch has no operations and repeats the previous sequence number.
+func (p *IncompleteJsonParser) Diff() (Patch, error) {
+	if p.scope == nil {
+		return Patch{Seq: p.patchSeq}, ErrNoInput
+	}
+
+	var ops []PatchOperation
+	if p.patchShadow == nil {
+		ops = append(ops, PatchOperation{Op: "add", Path: "", Value: copySnapshot(p.scope.GetOrAssume())})
+		p.patchShadow = newPatchShadow(p.scope)
+	} else if p.patchShadow.scope != p.scope {
+		// A new document
+		ops = append(ops, PatchOperation{Op: "replace", Path: "", Value: copySnapshot(p.scope.GetOrAssume())})
+		p.patchShadow = newPatchShadow(p.scope)
+	} else {
+		ops = p.patchShadow.diff(p.scope, Path{}, ops)
+	}
+
+	if len(ops) > 0 {
+		p.patchSeq++
+	}
+	return Patch{Seq: p.patchSeq, Operations: ops}, nil
+}
+
+// newPatchShadow records the current value of a scope
+func newPatchShadow(scope Scope) *patchShadow {
+	shadow := &patchShadow{scope: scope, kind: NodeNull}
+	if scope == nil {
+		return shadow
+	}
+	shadow.version = baseScope(scope).version
+	switch s := scope.(type) {
+	case *ObjectScope:
+		shadow.kind = NodeObject
+		shadow.members = make(map[string]*patchShadow)
+		keys := s.keys
+		if key, ok := s.shownPendingKey(); ok {
+			if _, exists := s.members[key]; !exists {
+				keys = append(keys[:len(keys):len(keys)], key)
+			}
+			shadow.pendingKey, shadow.hasPendingKey = key, true
+		}
+		for _, key := range keys {
+			scope, collected, _ := s.patchMember(key)
+			shadow.keys = append(shadow.keys, key)
+			shadow.members[key] = newMemberShadow(scope, collected)
+		}
+		shadow.cursor = s.cursor()
+	case *ArrayScope:
+		shadow.kind = NodeArray
+		for _, element := range s.array {
+			shadow.items = append(shadow.items, newPatchShadow(element))
+		}
+	case *LiteralScope:
+		shadow.kind = s.nodeKind()
+		shadow.value = s.GetOrAssume()
+	}
+	return shadow
+}
+
+// diff appends the operations that turn the recorded value into the current value of
+// scope and records the current value. scope is the same scope the shadow was taken from.
+func (shadow *patchShadow) diff(scope Scope, path Path, ops []PatchOperation) []PatchOperation {
+	if scope == nil || baseScope(scope).version == shadow.version {
+		return ops
+	}
+	shadow.version = baseScope(scope).version
+
+	switch s := scope.(type) {
+	case *ObjectScope:
+		// Only the member shown incomplete before, the members committed since and the
+		// member being written can differ from what the client holds
+		keys := make([]string, 0, 2)
+		if shadow.hasPendingKey {
+			keys = append(keys, shadow.pendingKey)
+		}
+		keys = append(keys, s.committedSince(shadow.cursor)...)
+		pendingKey, hasPendingKey := s.shownPendingKey()
+		if hasPendingKey {
+			keys = append(keys, pendingKey)
+		}
+		for _, key := range keys {
+			ops = shadow.diffMember(s, key, path, ops)
+		}
+		shadow.cursor = s.cursor()
+		shadow.pendingKey, shadow.hasPendingKey = pendingKey, hasPendingKey
+
+	case *ArrayScope:
+		// Elements before the last one recorded were moved past and are final
+		for i := max(len(shadow.items)-1, 0); i < len(s.array); i++ {
+			element := s.array[i]
+			elementPath := path.append(i)
+			if i < len(shadow.items) {
+				ops = shadow.items[i].diff(element, elementPath, ops)
+			} else {
+				ops = append(ops, PatchOperation{Op: "add", Path: elementPath.String(), Value: patchValue(element)})
+				shadow.items = append(shadow.items, newPatchShadow(element))
+			}
+		}
+
+	case *LiteralScope:
+		kind, value := s.nodeKind(), s.GetOrAssume()
+		if kind == NodeString && shadow.kind == NodeString {
+			previous, text := shadow.value.(string), value.(string)
+			if len(text) > len(previous) && text[:len(previous)] == previous {
+				ops = append(ops, PatchOperation{Op: "append", Path: path.String(), Value: text[len(previous):]})
+			}
+		} else if kind != shadow.kind || !reflect.DeepEqual(value, shadow.value) {
+			ops = append(ops, PatchOperation{Op: "replace", Path: path.String(), Value: value})
+		}
+		shadow.kind, shadow.value = kind, value
+	}
+	return ops
+}
+
+// diffMember appends the operations that turn the recorded value of the member with key into
+// the value a snapshot shows and records it
+func (shadow *patchShadow) diffMember(o *ObjectScope, key string, path Path, ops []PatchOperation) []PatchOperation {
+	scope, collected, shown := o.patchMember(key)
+	member, exists := shadow.members[key]
+	memberPath := path.append(key)
+	switch {
+	case !shown:
+		// A member shown incomplete before, whose key has grown since
+		if exists {
+			ops = append(ops, PatchOperation{Op: "remove", Path: memberPath.String()})
+			delete(shadow.members, key)
+			for i := len(shadow.keys) - 1; i >= 0; i-- {
+				if shadow.keys[i] == key {
+					shadow.keys = append(shadow.keys[:i], shadow.keys[i+1:]...)
+					break
+				}
+			}
+		}
+	case !exists:
+		ops = append(ops, PatchOperation{Op: "add", Path: memberPath.String(), Value: memberValue(scope, collected)})
+		shadow.keys = append(shadow.keys, key)
+		shadow.members[key] = newMemberShadow(scope, collected)
+	case collected != nil:
+		if !member.collecting || !reflect.DeepEqual(member.value, collected) {
+			ops = append(ops, PatchOperation{Op: "replace", Path: memberPath.String(), Value: memberValue(scope, collected)})
+			shadow.members[key] = newMemberShadow(scope, collected)
+		}
+	case member.collecting || member.scope != scope:
+		ops = append(ops, PatchOperation{Op: "replace", Path: memberPath.String(), Value: memberValue(scope, collected)})
+		shadow.members[key] = newMemberShadow(scope, collected)
+	default:
+		ops = member.diff(scope, memberPath, ops)
+	}
+	return ops
+}
+
+// patchMember returns how the member with key appears in a snapshot. scope is the scope of its
+// value, nil for the null of a member whose value has not started. While a repeated key is written
+// under DuplicateKeysCollect, the member shows the values gathered so far followed by the value
+// being written, which are returned as collected.
+func (o *ObjectScope) patchMember(key string) (scope Scope, collected interface{}, shown bool) {
+	committed, exists := o.members[key]
+	if pendingKey, ok := o.shownPendingKey(); !ok || pendingKey != key {
+		return committed, nil, exists
+	}
+	if !exists {
+		return o.valueScope, nil, true
+	}
+	switch o.ctx().duplicateKeys {
+	case DuplicateKeysFirstWins:
+		// The committed value stays until the repeated member is complete
+		return committed, nil, true
+	case DuplicateKeysCollect:
+		var value interface{}
+		if o.valueScope != nil {
+			value = o.valueScope.GetOrAssume()
+		}
+		collected, _ = o.pendingValue(key, value)
+		return nil, collected, true
+	}
+	return o.valueScope, nil, true
+}
+
+// shownPendingKey returns the key of the member being written once a snapshot shows it
+func (o *ObjectScope) shownPendingKey() (string, bool) {
+	if o.keyScope == nil {
+		return "", false
+	}
+	key, _ := o.keyScope.GetOrAssume().(string)
+	return key, key != ""
+}
+
+// newMemberShadow records the value of a member as patchMember describes it
+func newMemberShadow(scope Scope, collected interface{}) *patchShadow {
+	if collected != nil {
+		return &patchShadow{kind: NodeArray, value: copySnapshot(collected), collecting: true}
+	}
+	return newPatchShadow(scope)
+}
+
+// memberValue returns the value of a member for an operation
+func memberValue(scope Scope, collected interface{}) interface{} {
+	if collected != nil {
+		return copySnapshot(collected)
+	}
+	return patchValue(scope)
+}
+
+// patchValue returns the value of a scope for an operation
+func patchValue(scope Scope) interface{} {
+	if scope == nil {
+		return nil
+	}
+	return copySnapshot(scope.GetOrAssume())
+}
+
+// copySnapshot copies the maps and slices of a snapshot, which are shared with the parser's later
+// snapshots, so that clients may modify the values of operations as they apply them
+func copySnapshot(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for key, member := range value {
+			copied[key] = copySnapshot(member)
+		}
+		return copied
+	case *OrderedObject:
+		copied := NewOrderedObject()
+		for _, key := range value.keys {
+			copied.Set(key, copySnapshot(value.values[key]))
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, element := range value {
+			copied[i] = copySnapshot(element)
+		}
+		return copied
+	}
+	return v
+}
+
+// quoteJSON encodes a string as JSON
+func quoteJSON(s string) []byte {
+	encoded, _ := appendSnapshot(nil, s)
+	return encoded
+}
@@ -0,0 +1,123 @@
+package incompletejson
+
+import "errors"
+
+// Document is a top-level value read in multi-document mode
+type Document struct {
+	Index  int         // position of the document in the stream, starting at 0
+	Offset int64       // byte offset where the document starts
+	Value  interface{} // snapshot of the document; partial if Err is set
+	Err    error       // why the document was malformed, or nil if it is complete
+}
+
+// WithMultipleDocuments sets the option to read a stream of top-level values separated by whitespace
+// or simply concatenated. Each document is passed to the WithOnDocument function as soon as it is
+// complete, and the next value starts a new document. A malformed document is reported with its
+// error and skipped up to the end of its line, so Write does not fail on it.
+func WithMultipleDocuments(multiple bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.multipleDocuments = multiple
+	}
+}
+
+// WithNDJSON sets the option to read newline-delimited JSON: every line holds one document and a
+// line feed ends the document, so a line that stops early is reported as malformed. Characters
+// other than whitespace after the document are reported as a malformed document, and the rest of
+// the line is skipped. It implies WithMultipleDocuments.
+func WithNDJSON(ndjson bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.ndjson = ndjson
+		p.multipleDocuments = ndjson
+	}
+}
+
+// WithOnDocument sets a function called with every document read in multi-document mode
+func WithOnDocument(fn func(Document)) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.onDocument = fn
+	}
+}
+
+// writeStreamRune feeds a single character in multi-document mode
+func (p *IncompleteJsonParser) writeStreamRune(letter rune) error {
+	if p.skipping {
+		if letter == '\n' {
+			p.skipping = false
+		}
+		return nil
+	}
+
+	if p.ndjson && letter == '\n' {
+		if p.scope != nil && !p.finish || p.inComment() {
+			err := p.endDocument()
+			p.emitDocument(err)
+			if err != nil {
+				p.resetDocument()
+			}
+		}
+		p.comment = commentNone
+		p.lineEnded = p.finish
+		return nil
+	}
+
+	finished := p.finish
+	if p.scope == nil {
+		p.documentOffset = p.position.offset
+	}
+	err := p.writeDocumentRune(letter)
+	if errors.Is(err, ErrAlreadyFinished) && p.ndjson && !p.lineEnded {
+		// A line holds a single value, so the rest of the line is malformed
+		if !finished {
+			p.emitDocument(nil)
+		}
+		p.resetDocument()
+		p.documentOffset = p.position.offset
+		p.emitDocument(err)
+		p.skipping = true
+		return nil
+	}
+	if errors.Is(err, ErrAlreadyFinished) {
+		// The next document starts
+		p.resetDocument()
+		finished = false
+		p.documentOffset = p.position.offset
+		err = p.writeDocumentRune(letter)
+	}
+	if err != nil {
+		p.emitDocument(err)
+		p.resetDocument()
+		p.skipping = letter != '\n'
+		return nil
+	}
+	if p.scope != nil && p.finish && !finished {
+		p.emitDocument(nil)
+	}
+	return nil
+}
+
+// closeStream completes the last document of the stream
+func (p *IncompleteJsonParser) closeStream() error {
+	if p.scope == nil && !p.inComment() {
+		// Nothing follows the last document
+		return nil
+	}
+	if p.finish {
+		// The document has been reported already
+		return p.endDocument()
+	}
+	err := p.endDocument()
+	p.emitDocument(err)
+	return err
+}
+
+// emitDocument reports the current document to the WithOnDocument function and moves on to the next index
+func (p *IncompleteJsonParser) emitDocument(err error) {
+	if p.onDocument != nil {
+		document := Document{Index: p.documentIndex, Offset: p.documentOffset, Err: err}
+		if p.scope != nil {
+			document.Value = p.scope.GetOrAssume()
+		}
+		p.onDocument(document)
+	}
+	p.documentIndex++
+}
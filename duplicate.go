@@ -0,0 +1,27 @@
+package incompletejson
+
+import "fmt"
+
+// DuplicateKeyPolicy decides what happens when an object repeats a key
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysLastWins keeps the last value, like encoding/json (default)
+	DuplicateKeysLastWins DuplicateKeyPolicy = iota
+	// DuplicateKeysFirstWins keeps the first value and ignores later ones
+	DuplicateKeysFirstWins
+	// DuplicateKeysError fails with a *DuplicateKeyError
+	DuplicateKeysError
+	// DuplicateKeysCollect gathers all values of the key into an array
+	DuplicateKeysCollect
+)
+
+// DuplicateKeyError is returned when an object repeats a key under DuplicateKeysError
+type DuplicateKeyError struct {
+	Key  string
+	Path Path // path of the repeated member
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key %q at %s", e.Key, e.Path)
+}
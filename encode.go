@@ -0,0 +1,83 @@
+package incompletejson
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
+)
+
+// marshalSnapshot encodes a parsed result as JSON. Unlike json.Marshal it writes
+// *big.Float as a number instead of a string, so every number mode survives UnmarshalTo.
+func marshalSnapshot(v interface{}) ([]byte, error) {
+	return appendSnapshot(nil, v)
+}
+
+func appendSnapshot(buf []byte, v interface{}) ([]byte, error) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buf = append(buf, '{')
+		for i, key := range keys {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			encodedKey, err := json.Marshal(key)
+			if err != nil {
+				return nil, err
+			}
+			buf = append(append(buf, encodedKey...), ':')
+			if buf, err = appendSnapshot(buf, value[key]); err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, '}'), nil
+
+	case *OrderedObject:
+		buf = append(buf, '{')
+		for i, key := range value.keys {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			encodedKey, err := json.Marshal(key)
+			if err != nil {
+				return nil, err
+			}
+			buf = append(append(buf, encodedKey...), ':')
+			if buf, err = appendSnapshot(buf, value.values[key]); err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, '}'), nil
+
+	case []interface{}:
+		buf = append(buf, '[')
+		for i, element := range value {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			var err error
+			if buf, err = appendSnapshot(buf, element); err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, ']'), nil
+
+	case *big.Float:
+		if value.IsInf() {
+			return nil, errors.New("cannot marshal infinite number")
+		}
+		return value.Append(buf, 'g', -1), nil
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, encoded...), nil
+}
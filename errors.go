@@ -0,0 +1,72 @@
+package incompletejson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrAlreadyFinished is returned when non-whitespace follows a complete document
+	ErrAlreadyFinished = errors.New("parser is already finished")
+	// ErrNoInput is returned when a result is requested before any value has started
+	ErrNoInput = errors.New("no input to parse")
+	// ErrUnexpectedEnd is returned by Close when the input ends before the document is complete
+	ErrUnexpectedEnd = errors.New("unexpected end of JSON input")
+	// ErrNullValue is returned when null is unmarshalled into a typed target
+	ErrNullValue = errors.New("cannot unmarshal null into struct")
+)
+
+// ParseError describes where and why the input could not be parsed.
+// Use errors.As to retrieve it from the errors returned by Write.
+type ParseError struct {
+	Offset     int64  // byte offset of the offending character
+	RuneOffset int64  // character offset of the offending character
+	Line       int    // 1-based line of the offending character
+	Column     int    // 1-based column of the offending character, in characters
+	Path       Path   // path of the innermost value being parsed
+	Char       rune   // the offending character
+	Expected   string // description of what was expected instead, if known
+	Err        error  // underlying cause such as ErrAlreadyFinished, if any
+}
+
+func (e *ParseError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "failed to parse the JSON string at line %d, column %d (offset %d)", e.Line, e.Column, e.Offset)
+	if e.Err != nil {
+		sb.WriteString(": ")
+		sb.WriteString(e.Err.Error())
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, ": unexpected %q", e.Char)
+	if len(e.Path) > 0 {
+		fmt.Fprintf(&sb, " in %s", e.Path)
+	}
+	if e.Expected != "" {
+		fmt.Fprintf(&sb, ", expected %s", e.Expected)
+	}
+	return sb.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// position tracks where the next character sits in the input
+type position struct {
+	offset     int64
+	runeOffset int64
+	line       int
+	column     int
+}
+
+func (pos *position) advance(letter rune, size int) {
+	pos.offset += int64(size)
+	pos.runeOffset++
+	if letter == '\n' {
+		pos.line++
+		pos.column = 0
+	} else {
+		pos.column++
+	}
+}
@@ -0,0 +1,160 @@
+package incompletejson
+
+// EventKind identifies a token of the document
+type EventKind int
+
+const (
+	EventBeginObject EventKind = iota
+	EventEndObject
+	EventBeginArray
+	EventEndArray
+	EventKey
+	EventString
+	EventNumber
+	EventBool
+	EventNull
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventBeginObject:
+		return "BeginObject"
+	case EventEndObject:
+		return "EndObject"
+	case EventBeginArray:
+		return "BeginArray"
+	case EventEndArray:
+		return "EndArray"
+	case EventKey:
+		return "Key"
+	case EventString:
+		return "String"
+	case EventNumber:
+		return "Number"
+	case EventBool:
+		return "Bool"
+	}
+	return "Null"
+}
+
+// Event is a token of the document, reported while Write consumes the input
+type Event struct {
+	Kind EventKind
+	// Path is the path of the value; for EventKey it is the path of the member
+	Path Path
+	// Value is the key for EventKey, a fragment of the text for EventString, the number
+	// in the configured number mode for EventNumber and the bool for EventBool
+	Value interface{}
+	// Complete is false for a string fragment that more text will follow.
+	// A string ends with a complete fragment, which may be empty.
+	Complete bool
+}
+
+// WithOnEvent sets a function called with every token of the document as Write consumes it.
+// Strings are reported in fragments: the text written so far is reported at the end of every
+// Write call, and the rest when the string closes. Events do not build the snapshot tree, so a
+// consumer that only needs events never has to call GetObjects.
+func WithOnEvent(fn func(Event)) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.observers = append(p.scopeContext.observers, fn)
+	}
+}
+
+// observed reports whether any observer or subscription receives events
+func (c *scopeContext) observed() bool {
+	return len(c.observers) > 0 || len(c.subscriptions) > 0
+}
+
+// emit passes an event to every observer and subscription, dropping the subscriptions it completes
+func (c *scopeContext) emit(event Event) {
+	for _, observer := range c.observers {
+		observer(event)
+	}
+	active := c.subscriptions[:0]
+	for _, s := range c.subscriptions {
+		s.observe(event)
+		if s.complete {
+			s.context = nil
+		} else {
+			active = append(active, s)
+		}
+	}
+	clear(c.subscriptions[len(active):])
+	c.subscriptions = active
+}
+
+// unsubscribe removes a subscription from the context
+func (c *scopeContext) unsubscribe(s *Subscription) {
+	for i, subscription := range c.subscriptions {
+		if subscription == s {
+			last := len(c.subscriptions) - 1
+			copy(c.subscriptions[i:], c.subscriptions[i+1:])
+			c.subscriptions[last] = nil
+			c.subscriptions = c.subscriptions[:last]
+			break
+		}
+	}
+	s.context = nil
+}
+
+// begin reports a container that has just opened
+func (c *scopeContext) begin(scope Scope) {
+	if !c.observed() {
+		return
+	}
+	switch s := scope.(type) {
+	case *ObjectScope:
+		c.emit(Event{Kind: EventBeginObject, Path: s.path, Complete: true})
+	case *ArrayScope:
+		c.emit(Event{Kind: EventBeginArray, Path: s.path, Complete: true})
+	}
+}
+
+// key reports an object key that is complete
+func (c *scopeContext) key(path Path, key string) {
+	if c.observed() {
+		c.emit(Event{Kind: EventKey, Path: path, Value: key, Complete: true})
+	}
+}
+
+// end reports the end of a value
+func (c *scopeContext) end(scope Scope) {
+	if !c.observed() {
+		return
+	}
+	switch s := scope.(type) {
+	case *ObjectScope:
+		c.emit(Event{Kind: EventEndObject, Path: s.path, Complete: true})
+	case *ArrayScope:
+		c.emit(Event{Kind: EventEndArray, Path: s.path, Complete: true})
+	case *LiteralScope:
+		switch s.nodeKind() {
+		case NodeString:
+			s.emitText(true)
+		case NodeNumber:
+			c.emit(Event{Kind: EventNumber, Path: s.path, Value: s.GetOrAssume(), Complete: true})
+		case NodeBool:
+			c.emit(Event{Kind: EventBool, Path: s.path, Value: s.GetOrAssume(), Complete: true})
+		default:
+			c.emit(Event{Kind: EventNull, Path: s.path, Complete: true})
+		}
+	}
+}
+
+// emitText reports the text of a string value written since the previous fragment
+func (l *LiteralScope) emitText(complete bool) {
+	text := l.text()
+	l.ctx().emit(Event{Kind: EventString, Path: l.path, Value: text[l.emitted:], Complete: complete})
+	l.emitted = len(text)
+}
+
+// flushEvents reports the text of the string being written, at the end of a Write call
+func (p *IncompleteJsonParser) flushEvents() {
+	if !p.scopeContext.observed() {
+		return
+	}
+	literal := activeLiteral(p.scope)
+	if literal != nil && literal.kind == literalString && !literal.key && len(literal.content) > literal.emitted {
+		literal.emitText(false)
+	}
+}
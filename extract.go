@@ -0,0 +1,153 @@
+package incompletejson
+
+import "strings"
+
+// extractState tracks where the parser is in text that embeds JSON
+type extractState int
+
+const (
+	extractProse      extractState = iota // in text outside of JSON
+	extractFenceInfo                      // reading the info string of an opening fence
+	extractFenced                         // inside a JSON code block
+	extractFenceClose                     // inside the closing fence of a JSON code block
+	extractSkipFence                      // inside a code block in another language
+	extractBare                           // inside a JSON value found in text
+)
+
+// WithEmbeddedJSON sets the option to find JSON embedded in prose, such as a model reply that wraps
+// its answer in text. Leading text is skipped; a Markdown code fence tagged json (or untagged), or
+// else the first '{' or '[', starts a document, which is streamed through the normal scopes up to
+// the closing fence or its closing bracket. Each document is passed to the WithOnDocument function.
+// A bracket in the text that does not start valid JSON is treated as text.
+func WithEmbeddedJSON(extract bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.extract = extract
+	}
+}
+
+// writeExtractRune feeds a single character of text that embeds JSON
+func (p *IncompleteJsonParser) writeExtractRune(letter rune) error {
+	switch p.extractState {
+	case extractProse:
+		if letter == '`' {
+			p.backticks++
+			if p.backticks == 3 {
+				p.backticks = 0
+				p.fenceInfo.Reset()
+				p.extractState = extractFenceInfo
+			}
+			return nil
+		}
+		p.backticks = 0
+		if letter == '{' || letter == '[' {
+			p.startExtractedDocument(extractBare)
+			return p.writeExtractRune(letter)
+		}
+
+	case extractFenceInfo:
+		if letter != '\n' {
+			p.fenceInfo.WriteRune(letter)
+			return nil
+		}
+		if p.isJSONFence(p.fenceInfo.String()) {
+			p.startExtractedDocument(extractFenced)
+		} else {
+			p.extractState = extractSkipFence
+		}
+
+	case extractFenced:
+		if letter == '`' && !p.inString() {
+			// The closing fence ends the document
+			if p.scope != nil && !p.finish {
+				p.emitDocument(p.endDocument())
+			}
+			p.backticks = 1
+			p.extractState = extractFenceClose
+			return nil
+		}
+		if p.finish {
+			// Text after the document inside the code block is ignored
+			return nil
+		}
+		if p.scope == nil {
+			p.documentOffset = p.position.offset
+		}
+		if err := p.writeDocumentRune(letter); err != nil {
+			p.emitDocument(err)
+			p.resetDocument()
+			p.extractState = extractSkipFence
+			return nil
+		}
+		if p.scope != nil && p.finish {
+			p.emitDocument(nil)
+		}
+
+	case extractFenceClose:
+		if letter == '`' {
+			return nil
+		}
+		p.extractState = extractProse
+		return p.writeExtractRune(letter)
+
+	case extractSkipFence:
+		if letter != '`' {
+			p.backticks = 0
+			return nil
+		}
+		p.backticks++
+		if p.backticks == 3 {
+			p.backticks = 0
+			p.extractState = extractFenceClose
+		}
+
+	case extractBare:
+		// A bracket in prose starts a document only if it holds valid JSON: a character that
+		// lenient scopes tolerate after an inner value rejected it, as in "[this]", ends the attempt
+		if err := p.writeDocumentRune(letter); err != nil || p.scopeContext.rejected {
+			// Not JSON after all
+			p.resetDocument()
+			p.extractState = extractProse
+			return nil
+		}
+		if p.finish {
+			p.emitDocument(nil)
+			p.extractState = extractProse
+		}
+	}
+	return nil
+}
+
+// startExtractedDocument starts a new document in the given state
+func (p *IncompleteJsonParser) startExtractedDocument(state extractState) {
+	p.resetDocument()
+	p.documentOffset = p.position.offset
+	p.extractState = state
+}
+
+// closeExtract completes a document left open at the end of the text
+func (p *IncompleteJsonParser) closeExtract() error {
+	if (p.extractState != extractFenced && p.extractState != extractBare) || p.scope == nil || p.finish {
+		return nil
+	}
+	err := p.endDocument()
+	p.emitDocument(err)
+	return err
+}
+
+// isJSONFence reports whether a code block with the given info string holds a document
+func (p *IncompleteJsonParser) isJSONFence(info string) bool {
+	language := strings.ToLower(strings.TrimSpace(info))
+	switch language {
+	case "", "json", "jsonc", "json5":
+		return true
+	case "python", "py":
+		return p.scopeContext.pythonLiterals
+	}
+	return false
+}
+
+// inString reports whether the current document is inside a string
+func (p *IncompleteJsonParser) inString() bool {
+	literal := activeLiteral(p.scope)
+	return literal != nil && literal.kind == literalString
+}
@@ -0,0 +1,185 @@
+package incompletejson
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// structField describes how an object member maps onto a struct field
+type structField struct {
+	name      string
+	index     []int // index sequence for reflect.Value.FieldByIndex
+	typ       reflect.Type
+	tagged    bool // the name comes from the json tag
+	omitEmpty bool
+	quoted    bool // the ",string" option: the value is encoded inside a JSON string
+}
+
+// structFields is the decoding plan of a struct type
+type structFields struct {
+	list   []structField
+	byName map[string]*structField
+	byFold map[string]*structField // lower-cased names for case-insensitive matches
+}
+
+// fieldCache holds the plan of every struct type decoded so far
+var fieldCache sync.Map // map[reflect.Type]*structFields
+
+// cachedFields returns the decoding plan of a struct type, following the rules of encoding/json
+func cachedFields(t reflect.Type) *structFields {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.(*structFields)
+	}
+	fields, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return fields.(*structFields)
+}
+
+// lookup returns the field for a member key, preferring an exact match to a case-insensitive one
+func (f *structFields) lookup(key string) *structField {
+	if field, ok := f.byName[key]; ok {
+		return field
+	}
+	return f.byFold[strings.ToLower(key)]
+}
+
+// typeFields collects the fields of a struct type, including those promoted from embedded
+// structs. Like encoding/json, a shallower field hides deeper ones of the same name, and
+// among fields at the same depth a tagged one wins; otherwise the name is ambiguous and dropped.
+func typeFields(t reflect.Type) *structFields {
+	var candidates []structField
+
+	type level struct {
+		typ   reflect.Type
+		index []int
+	}
+	current := []level{{typ: t}}
+	visited := map[reflect.Type]bool{}
+	for len(current) > 0 {
+		var next []level
+		for _, l := range current {
+			if visited[l.typ] {
+				continue
+			}
+			visited[l.typ] = true
+
+			for i := 0; i < l.typ.NumField(); i++ {
+				field := l.typ.Field(i)
+				fieldType := field.Type
+				if field.Anonymous && fieldType.Kind() == reflect.Ptr {
+					fieldType = fieldType.Elem()
+				}
+				if !field.IsExported() && !(field.Anonymous && fieldType.Kind() == reflect.Struct) {
+					continue
+				}
+				tag := field.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, options, _ := strings.Cut(tag, ",")
+				index := append(l.index[:len(l.index):len(l.index)], i)
+
+				if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+					// Promote the fields of an embedded struct
+					next = append(next, level{typ: fieldType, index: index})
+					continue
+				}
+				if !field.IsExported() {
+					continue
+				}
+
+				candidate := structField{tagged: name != ""}
+				if name == "" {
+					name = field.Name
+				}
+				candidate.name = name
+				candidate.index = index
+				candidate.typ = field.Type
+				for _, option := range strings.Split(options, ",") {
+					switch option {
+					case "omitempty":
+						candidate.omitEmpty = true
+					case "string":
+						candidate.quoted = isQuotable(field.Type)
+					}
+				}
+				candidates = append(candidates, candidate)
+			}
+		}
+		current = next
+	}
+
+	// Keep the dominant field of every name: candidates are ordered by depth
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].index) < len(candidates[j].index)
+	})
+	dominant := map[string]int{}
+	ambiguous := map[string]bool{}
+	var kept []structField
+	for _, c := range candidates {
+		i, seen := dominant[c.name]
+		if !seen {
+			dominant[c.name] = len(kept)
+			kept = append(kept, c)
+			continue
+		}
+		other := &kept[i]
+		if len(other.index) < len(c.index) {
+			continue
+		}
+		switch {
+		case other.tagged && !c.tagged:
+		case c.tagged && !other.tagged:
+			*other = c
+			delete(ambiguous, c.name)
+		default:
+			ambiguous[c.name] = true
+		}
+	}
+
+	fields := &structFields{byName: map[string]*structField{}, byFold: map[string]*structField{}}
+	for _, c := range kept {
+		if !ambiguous[c.name] {
+			fields.list = append(fields.list, c)
+		}
+	}
+	sort.SliceStable(fields.list, func(i, j int) bool {
+		return lessIndex(fields.list[i].index, fields.list[j].index)
+	})
+	for i := range fields.list {
+		field := &fields.list[i]
+		fields.byName[field.name] = field
+		if _, ok := fields.byFold[strings.ToLower(field.name)]; !ok {
+			fields.byFold[strings.ToLower(field.name)] = field
+		}
+	}
+	return fields
+}
+
+// lessIndex orders fields by their position in the struct declaration
+func lessIndex(a, b []int) bool {
+	for i := range a {
+		if i >= len(b) {
+			return false
+		}
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// isQuotable reports whether the ",string" option applies to a field type
+func isQuotable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
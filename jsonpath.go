@@ -0,0 +1,614 @@
+package incompletejson
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Query evaluates a JSONPath query and returns the selected values in document order.
+// Only the selected values are built, never the whole document. The supported subset of
+// RFC 9535 covers member names (.name, ['name']), array indexes ([0], [-1]), wildcards
+// (.*, [*]), descendants (..name), unions ([0,'a']) and filters such as
+// [?@.price < 10 && @.tags] with the comparisons ==, !=, <, <=, > and >=.
+//
+// A filter only relies on values that are final: while a compared value is still being
+// written, or a tested member may still arrive, the filter does not select the value.
+func (p *IncompleteJsonParser) Query(query string) ([]Result, error) {
+	segments, err := parseJSONPath(query)
+	if err != nil {
+		return nil, err
+	}
+	if p.scope == nil {
+		return nil, nil
+	}
+
+	nodes := []queryNode{rootNode(p.scope)}
+	for _, segment := range segments {
+		nodes = segment.apply(nodes)
+	}
+
+	results := make([]Result, 0, len(nodes))
+	for _, node := range nodes {
+		results = append(results, node.result())
+	}
+	return results, nil
+}
+
+// pathSegment selects values from each input node, or from each of their descendants
+type pathSegment struct {
+	descendant bool
+	selectors  []pathSelector
+}
+
+type selectorKind int
+
+const (
+	selectName selectorKind = iota
+	selectIndex
+	selectWildcard
+	selectFilter
+)
+
+type pathSelector struct {
+	kind   selectorKind
+	name   string
+	index  int
+	filter filterExpr
+}
+
+func (s pathSegment) apply(nodes []queryNode) []queryNode {
+	var selected []queryNode
+	for _, node := range nodes {
+		targets := []queryNode{node}
+		if s.descendant {
+			targets = node.descendants(nil)
+		}
+		for _, target := range targets {
+			for _, selector := range s.selectors {
+				selected = selector.apply(target, selected)
+			}
+		}
+	}
+	return selected
+}
+
+// apply appends the values the selector picks from node to selected
+func (s pathSelector) apply(node queryNode, selected []queryNode) []queryNode {
+	switch s.kind {
+	case selectName:
+		if child, ok := node.member(s.name); ok {
+			selected = append(selected, child)
+		}
+	case selectIndex:
+		if child, ok := node.element(s.index); ok {
+			selected = append(selected, child)
+		}
+	case selectWildcard:
+		selected = append(selected, node.children()...)
+	case selectFilter:
+		for _, child := range node.children() {
+			if s.filter.eval(child) == truthTrue {
+				selected = append(selected, child)
+			}
+		}
+	}
+	return selected
+}
+
+// truth is the outcome of a filter on a partial document, where values still
+// being written are not known yet
+type truth int
+
+const (
+	truthFalse truth = iota
+	truthTrue
+	truthUnknown
+)
+
+func truthOf(b bool) truth {
+	if b {
+		return truthTrue
+	}
+	return truthFalse
+}
+
+// filterExpr is a filter selector's logical expression
+type filterExpr interface {
+	eval(node queryNode) truth
+}
+
+type orFilter struct{ left, right filterExpr }
+
+func (f orFilter) eval(node queryNode) truth {
+	left, right := f.left.eval(node), f.right.eval(node)
+	switch {
+	case left == truthTrue || right == truthTrue:
+		return truthTrue
+	case left == truthUnknown || right == truthUnknown:
+		return truthUnknown
+	}
+	return truthFalse
+}
+
+type andFilter struct{ left, right filterExpr }
+
+func (f andFilter) eval(node queryNode) truth {
+	left, right := f.left.eval(node), f.right.eval(node)
+	switch {
+	case left == truthFalse || right == truthFalse:
+		return truthFalse
+	case left == truthUnknown || right == truthUnknown:
+		return truthUnknown
+	}
+	return truthTrue
+}
+
+type notFilter struct{ expr filterExpr }
+
+func (f notFilter) eval(node queryNode) truth {
+	switch f.expr.eval(node) {
+	case truthTrue:
+		return truthFalse
+	case truthFalse:
+		return truthTrue
+	}
+	return truthUnknown
+}
+
+// existsFilter tests whether a relative query finds a value
+type existsFilter struct{ query filterOperand }
+
+func (f existsFilter) eval(node queryNode) truth {
+	switch f.query.evaluate(node).state {
+	case operandMissing:
+		return truthFalse
+	case operandUnknown:
+		return truthUnknown
+	}
+	return truthTrue
+}
+
+type compareFilter struct {
+	op          string
+	left, right filterOperand
+}
+
+func (f compareFilter) eval(node queryNode) truth {
+	left, right := f.left.evaluate(node), f.right.evaluate(node)
+	if left.state == operandPartial || left.state == operandUnknown ||
+		right.state == operandPartial || right.state == operandUnknown {
+		return truthUnknown
+	}
+
+	switch f.op {
+	case "==":
+		return truthOf(left.equal(right))
+	case "!=":
+		return truthOf(!left.equal(right))
+	}
+	if left.state == operandMissing || right.state == operandMissing {
+		return truthFalse
+	}
+	switch l := left.value.(type) {
+	case float64:
+		if r, ok := right.value.(float64); ok {
+			return truthOf(compareOrder(f.op, l < r, l == r))
+		}
+	case string:
+		if r, ok := right.value.(string); ok {
+			return truthOf(compareOrder(f.op, l < r, l == r))
+		}
+	}
+	return truthFalse
+}
+
+// compareOrder applies an ordering operator given whether the left operand is less than or equal to the right
+func compareOrder(op string, less, equal bool) bool {
+	switch op {
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	}
+	return !less
+}
+
+type operandState int
+
+const (
+	operandFinal   operandState = iota // the value is known
+	operandPartial                     // the value exists but is still being written
+	operandMissing                     // the value does not exist and never will
+	operandUnknown                     // the value does not exist yet but may still arrive
+)
+
+type operandValue struct {
+	value interface{}
+	state operandState
+}
+
+func (v operandValue) equal(other operandValue) bool {
+	if v.state == operandMissing || other.state == operandMissing {
+		return v.state == other.state
+	}
+	return reflect.DeepEqual(v.value, other.value)
+}
+
+// filterOperand is a literal or a query relative to the value being filtered
+type filterOperand struct {
+	relative bool
+	query    []pathSegment // singular: one name or index selector per segment
+	literal  interface{}
+}
+
+func (o filterOperand) evaluate(node queryNode) operandValue {
+	if !o.relative {
+		return operandValue{value: o.literal}
+	}
+
+	for _, segment := range o.query {
+		var child queryNode
+		var ok bool
+		selector := segment.selectors[0]
+		if selector.kind == selectIndex {
+			child, ok = node.element(selector.index)
+		} else {
+			child, ok = node.member(selector.name)
+		}
+		if !ok {
+			// Members and elements can still be added to a container that is being written
+			_, isObject := node.scope.(*ObjectScope)
+			_, isArray := node.scope.(*ArrayScope)
+			if !node.complete && ((isObject && selector.kind == selectName) || (isArray && selector.kind == selectIndex)) {
+				return operandValue{state: operandUnknown}
+			}
+			return operandValue{state: operandMissing}
+		}
+		node = child
+	}
+
+	if !node.complete {
+		return operandValue{state: operandPartial}
+	}
+	return operandValue{value: filterValue(node.scope)}
+}
+
+// filterValue returns the value of a scope for comparisons, with every number as a float64
+func filterValue(scope Scope) interface{} {
+	literal, ok := scope.(*LiteralScope)
+	if !ok {
+		return scope.GetOrAssume()
+	}
+	switch literal.kind {
+	case literalNumber:
+		number, _ := strconv.ParseFloat(literal.numberLexeme(), 64)
+		return number
+	case literalString, literalIdentifier:
+		return literal.text()
+	}
+	return literal.GetOrAssume()
+}
+
+// pathParser parses a JSONPath query
+type pathParser struct {
+	query string
+	pos   int
+}
+
+func parseJSONPath(query string) ([]pathSegment, error) {
+	pp := &pathParser{query: query}
+	if !pp.consume("$") {
+		return nil, pp.fail("a JSONPath query must start with '$'")
+	}
+	segments, err := pp.segments()
+	if err != nil {
+		return nil, err
+	}
+	if pp.pos < len(query) {
+		return nil, pp.fail("unexpected character")
+	}
+	return segments, nil
+}
+
+func (pp *pathParser) fail(reason string) error {
+	return &QueryError{Query: pp.query, Offset: pp.pos, Reason: reason}
+}
+
+func (pp *pathParser) peek() byte {
+	if pp.pos < len(pp.query) {
+		return pp.query[pp.pos]
+	}
+	return 0
+}
+
+func (pp *pathParser) consume(token string) bool {
+	if strings.HasPrefix(pp.query[pp.pos:], token) {
+		pp.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (pp *pathParser) skipSpaces() {
+	for pp.pos < len(pp.query) && isJSONWhitespace(rune(pp.query[pp.pos])) {
+		pp.pos++
+	}
+}
+
+// segments parses the segments that follow '$' or '@'
+func (pp *pathParser) segments() ([]pathSegment, error) {
+	var segments []pathSegment
+	for {
+		var segment pathSegment
+		var err error
+		switch {
+		case pp.consume(".."):
+			segment.descendant = true
+			if pp.consume("[") {
+				segment.selectors, err = pp.bracket()
+			} else {
+				segment.selectors, err = pp.shorthand()
+			}
+		case pp.consume("."):
+			segment.selectors, err = pp.shorthand()
+		case pp.consume("["):
+			segment.selectors, err = pp.bracket()
+		default:
+			return segments, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+}
+
+// shorthand parses the wildcard or member name after '.'
+func (pp *pathParser) shorthand() ([]pathSelector, error) {
+	if pp.consume("*") {
+		return []pathSelector{{kind: selectWildcard}}, nil
+	}
+	start := pp.pos
+	for pp.pos < len(pp.query) {
+		c := pp.query[pp.pos]
+		if !(c == '_' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || (pp.pos > start && '0' <= c && c <= '9')) {
+			break
+		}
+		pp.pos++
+	}
+	if pp.pos == start {
+		return nil, pp.fail("expected member name or '*'")
+	}
+	return []pathSelector{{kind: selectName, name: pp.query[start:pp.pos]}}, nil
+}
+
+// bracket parses the selectors after '[' up to the closing ']'
+func (pp *pathParser) bracket() ([]pathSelector, error) {
+	var selectors []pathSelector
+	for {
+		pp.skipSpaces()
+		selector, err := pp.selector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+		pp.skipSpaces()
+		if pp.consume("]") {
+			return selectors, nil
+		}
+		if !pp.consume(",") {
+			return nil, pp.fail("expected ',' or ']'")
+		}
+	}
+}
+
+func (pp *pathParser) selector() (pathSelector, error) {
+	switch c := pp.peek(); {
+	case c == '*':
+		pp.pos++
+		return pathSelector{kind: selectWildcard}, nil
+	case c == '\'' || c == '"':
+		name, err := pp.string()
+		return pathSelector{kind: selectName, name: name}, err
+	case c == '?':
+		pp.pos++
+		filter, err := pp.orExpr()
+		return pathSelector{kind: selectFilter, filter: filter}, err
+	case c == '-' || isDigit(rune(c)):
+		start := pp.pos
+		pp.pos++
+		for isDigit(rune(pp.peek())) {
+			pp.pos++
+		}
+		index, err := strconv.Atoi(pp.query[start:pp.pos])
+		if err != nil {
+			pp.pos = start
+			return pathSelector{}, pp.fail("invalid array index")
+		}
+		return pathSelector{kind: selectIndex, index: index}, nil
+	}
+	return pathSelector{}, pp.fail("expected selector")
+}
+
+// string parses a quoted string with JSON escapes, in single or double quotes
+func (pp *pathParser) string() (string, error) {
+	quote := pp.query[pp.pos]
+	pp.pos++
+	var sb strings.Builder
+	for pp.pos < len(pp.query) {
+		c := pp.query[pp.pos]
+		switch {
+		case c == quote:
+			pp.pos++
+			return sb.String(), nil
+		case c == '\\':
+			r, err := pp.escape()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte(c)
+			pp.pos++
+		}
+	}
+	return "", pp.fail("unterminated string")
+}
+
+// escape parses an escape sequence starting at the backslash
+func (pp *pathParser) escape() (rune, error) {
+	pp.pos++
+	c := pp.peek()
+	pp.pos++
+	switch c {
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case '\\', '/', '\'', '"':
+		return rune(c), nil
+	case 'u':
+		r, ok := pp.hex4()
+		if ok && utf16.IsSurrogate(r) && pp.consume(`\u`) {
+			low, ok := pp.hex4()
+			if !ok {
+				return 0, pp.fail("invalid unicode escape")
+			}
+			return utf16.DecodeRune(r, low), nil
+		}
+		if ok {
+			return r, nil
+		}
+		return 0, pp.fail("invalid unicode escape")
+	}
+	pp.pos--
+	return 0, pp.fail("invalid escape sequence")
+}
+
+func (pp *pathParser) hex4() (rune, bool) {
+	if pp.pos+4 > len(pp.query) {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(pp.query[pp.pos:pp.pos+4], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	pp.pos += 4
+	return rune(value), true
+}
+
+// orExpr parses a filter expression: or-expressions of and-expressions of unary expressions
+func (pp *pathParser) orExpr() (filterExpr, error) {
+	left, err := pp.andExpr()
+	for err == nil {
+		pp.skipSpaces()
+		if !pp.consume("||") {
+			return left, nil
+		}
+		var right filterExpr
+		right, err = pp.andExpr()
+		left = orFilter{left, right}
+	}
+	return nil, err
+}
+
+func (pp *pathParser) andExpr() (filterExpr, error) {
+	left, err := pp.unaryExpr()
+	for err == nil {
+		pp.skipSpaces()
+		if !pp.consume("&&") {
+			return left, nil
+		}
+		var right filterExpr
+		right, err = pp.unaryExpr()
+		left = andFilter{left, right}
+	}
+	return nil, err
+}
+
+func (pp *pathParser) unaryExpr() (filterExpr, error) {
+	pp.skipSpaces()
+	if pp.consume("!") {
+		expr, err := pp.unaryExpr()
+		return notFilter{expr}, err
+	}
+	if pp.consume("(") {
+		expr, err := pp.orExpr()
+		if err != nil {
+			return nil, err
+		}
+		pp.skipSpaces()
+		if !pp.consume(")") {
+			return nil, pp.fail("expected ')'")
+		}
+		return expr, nil
+	}
+
+	left, err := pp.operand()
+	if err != nil {
+		return nil, err
+	}
+	pp.skipSpaces()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if pp.consume(op) {
+			pp.skipSpaces()
+			right, err := pp.operand()
+			return compareFilter{op: op, left: left, right: right}, err
+		}
+	}
+	if !left.relative {
+		return nil, pp.fail("expected comparison")
+	}
+	return existsFilter{left}, nil
+}
+
+// operand parses a relative query or a literal in a filter
+func (pp *pathParser) operand() (filterOperand, error) {
+	start := pp.pos
+	switch c := pp.peek(); {
+	case c == '@':
+		pp.pos++
+		query, err := pp.segments()
+		if err != nil {
+			return filterOperand{}, err
+		}
+		for _, segment := range query {
+			if segment.descendant || len(segment.selectors) != 1 ||
+				(segment.selectors[0].kind != selectName && segment.selectors[0].kind != selectIndex) {
+				pp.pos = start
+				return filterOperand{}, pp.fail("a filter query must address a single value")
+			}
+		}
+		return filterOperand{relative: true, query: query}, nil
+	case c == '\'' || c == '"':
+		text, err := pp.string()
+		return filterOperand{literal: text}, err
+	case c == '-' || isDigit(rune(c)):
+		for pp.pos < len(pp.query) && strings.IndexByte("0123456789.eE+-", pp.query[pp.pos]) >= 0 {
+			pp.pos++
+		}
+		number, err := strconv.ParseFloat(pp.query[start:pp.pos], 64)
+		if err != nil {
+			pp.pos = start
+			return filterOperand{}, pp.fail("invalid number")
+		}
+		return filterOperand{literal: number}, nil
+	case pp.consume("true"):
+		return filterOperand{literal: true}, nil
+	case pp.consume("false"):
+		return filterOperand{literal: false}, nil
+	case pp.consume("null"):
+		return filterOperand{literal: nil}, nil
+	}
+	return filterOperand{}, pp.fail("expected '@', string, number, true, false or null")
+}
@@ -1,16 +1,104 @@
 package incompletejson
 
 import (
-	"encoding/json"
-	"regexp"
-	"strconv"
+	"math"
+	"math/big"
 	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
 )
 
-// LiteralScope handles parsing of literal values (strings, numbers, booleans, null)
+// literalKind identifies what kind of literal a LiteralScope is lexing
+type literalKind int
+
+const (
+	literalUnknown literalKind = iota
+	literalString
+	literalNumber
+	literalKeyword
+	literalIdentifier // unquoted object key
+)
+
+// escapeState tracks an escape sequence inside a string
+type escapeState int
+
+const (
+	escapeNone           escapeState = iota
+	escapeStart                      // after a backslash
+	escapeUnicode                    // inside \uXXXX
+	escapeHex                        // inside \xXX or \UXXXXXXXX
+	escapeOctal                      // inside a Python octal escape \ooo
+	escapeCarriageReturn             // after a backslash and a carriage return, which may be followed by a line feed
+)
+
+// numberState tracks the position inside a number (RFC 8259 section 6)
+type numberState int
+
+const (
+	numberSign         numberState = iota // after a leading minus
+	numberZero                            // after a leading zero, which cannot be followed by digits
+	numberInteger                         // inside the integer part
+	numberDot                             // after the decimal point
+	numberFraction                        // inside the fraction part
+	numberExponentMark                    // after e or E
+	numberExponentSign                    // after the sign of the exponent
+	numberExponent                        // inside the exponent digits
+	numberLeadingDot                      // after a decimal point with no integer part (JSON5)
+	numberHexMark                         // after 0x (JSON5)
+	numberHex                             // inside hexadecimal digits (JSON5)
+)
+
+// keyword is a bare word literal and the value it stands for
+type keyword struct {
+	name  string
+	value interface{}
+}
+
+var jsonKeywords = []keyword{
+	{"null", nil},
+	{"true", true},
+	{"false", false},
+}
+
+var json5Keywords = append(jsonKeywords[:len(jsonKeywords):len(jsonKeywords)],
+	keyword{"Infinity", math.Inf(1)},
+	keyword{"+Infinity", math.Inf(1)},
+	keyword{"-Infinity", math.Inf(-1)},
+	keyword{"NaN", math.NaN()},
+	keyword{"+NaN", math.NaN()},
+	keyword{"-NaN", math.NaN()},
+)
+
+var pythonKeywords = []keyword{
+	{"True", true},
+	{"False", false},
+	{"None", nil},
+}
+
+// LiteralScope handles parsing of literal values (strings, numbers, booleans, null).
+// It lexes incrementally: every rune is processed once and the decoded string
+// is kept in a growable buffer, so streaming a long value costs linear time.
 type LiteralScope struct {
 	BaseScope
-	content string
+	kind literalKind
+	// content holds the decoded text for strings and the raw lexeme for numbers and keywords
+	content []byte
+
+	quote         rune // character that closes the string
+	escape        escapeState
+	unicode       rune // code unit accumulated from the digits of \uXXXX, \xXX or \ooo
+	unicodeDigits int
+	hexDigits     int  // number of digits of a \xXX or \UXXXXXXXX escape
+	highSurrogate rune // pending high surrogate waiting for its low half
+
+	number numberState
+
+	key     bool // the literal is an object key
+	emitted int  // length of the text already reported in string events
+
+	value interface{} // cached result of GetOrAssume
 }
 
 func NewLiteralScope() *LiteralScope {
@@ -21,223 +109,505 @@ func (l *LiteralScope) Write(letter rune) bool {
 	if l.finish {
 		return false
 	}
-
-	l.content += string(letter)
-
-	// For null values, we need to check the actual return value differently
-	// since assume can be nil for valid null values
-	if l.content != "" && !l.canParseContent() {
-		l.content = l.content[:len(l.content)-1]
-		return false
+	l.touch()
+
+	switch l.kind {
+	case literalString:
+		return l.writeString(letter)
+	case literalNumber:
+		return l.writeNumber(letter)
+	case literalKeyword:
+		return l.writeKeyword(letter)
+	case literalIdentifier:
+		return l.writeIdentifier(letter)
 	}
 
-	// Check if literal is complete
-	if (l.isCompletedString()) ||
-		(l.content == "true" || l.content == "false") ||
-		(l.content == "null") {
-		l.finish = true
+	// First letter decides the kind of literal
+	switch {
+	case letter == '"' || (letter == '\'' && l.ctx().singleQuotes):
+		l.kind = literalString
+		l.quote = letter
+		return true
+	case letter == '-' || isDigit(letter) || (l.ctx().json5 && (letter == '+' || letter == '.')):
+		l.kind = literalNumber
+		l.number = numberSign
+		return l.writeNumber(letter)
+	}
+	l.kind = literalKeyword
+	if !l.writeKeyword(letter) {
+		l.kind = literalUnknown
+		return l.reject("value")
 	}
-
 	return true
 }
 
-func (l *LiteralScope) canParseContent() bool {
-	// Empty content is valid (assumes null)
-	if l.content == "" {
+func (l *LiteralScope) writeString(letter rune) bool {
+	switch l.escape {
+	case escapeStart:
+		if l.ctx().pythonLiterals {
+			return l.writePythonEscape(letter)
+		}
+		if letter == 'u' {
+			l.escape = escapeUnicode
+			l.unicode = 0
+			l.unicodeDigits = 0
+			return true
+		}
+		if l.ctx().json5 {
+			switch letter {
+			case 'x':
+				l.startHexEscape(2)
+				return true
+			case '\n', '\u2028', '\u2029':
+				// Line continuation
+				l.escape = escapeNone
+				return true
+			case '\r':
+				l.escape = escapeCarriageReturn
+				return true
+			}
+		}
+		decoded, ok := l.unescape(letter)
+		if !ok {
+			return l.reject("escape character")
+		}
+		l.escape = escapeNone
+		l.appendRune(decoded)
 		return true
-	}
 
-	// Check if it's a valid partial/complete null
-	if strings.HasPrefix("null", l.content) {
+	case escapeUnicode:
+		digit, ok := hexValue(letter)
+		if !ok {
+			return l.reject("hexadecimal digit")
+		}
+		l.unicode = l.unicode<<4 | digit
+		l.unicodeDigits++
+		if l.unicodeDigits == 4 {
+			l.escape = escapeNone
+			l.appendCodeUnit(l.unicode)
+		}
 		return true
-	}
 
-	// Check if it's a valid partial/complete boolean
-	if strings.HasPrefix("true", l.content) || strings.HasPrefix("false", l.content) {
+	case escapeHex:
+		digit, ok := hexValue(letter)
+		if !ok {
+			return l.reject("hexadecimal digit")
+		}
+		l.unicode = l.unicode<<4 | digit
+		l.unicodeDigits++
+		if l.unicodeDigits == l.hexDigits {
+			l.escape = escapeNone
+			l.appendRune(l.unicode)
+		}
 		return true
-	}
 
-	// Check if it's a valid string (starts with quote)
-	if strings.HasPrefix(l.content, `"`) {
-		// If allowUnescapedNewlines is disabled, check for unescaped newlines
-		if !l.allowUnescapedNewlines {
-			// Check for unescaped newlines, carriage returns, or tabs
-			for i := 1; i < len(l.content); i++ { // Skip opening quote
-				char := l.content[i]
-				if char == '\n' || char == '\r' || char == '\t' {
-					// Check if it's escaped by counting preceding backslashes
-					backslashes := 0
-					for j := i - 1; j >= 1; j-- {
-						if l.content[j] == '\\' {
-							backslashes++
-						} else {
-							break
-						}
-					}
-					// If even number of backslashes (or no backslashes), it's unescaped
-					if backslashes%2 == 0 {
-						return false // Unescaped newline/tab found
-					}
-				}
+	case escapeOctal:
+		if letter >= '0' && letter <= '7' {
+			l.unicode = l.unicode<<3 | (letter - '0')
+			l.unicodeDigits++
+			if l.unicodeDigits == 3 {
+				l.escape = escapeNone
+				l.appendRune(l.unicode)
 			}
+			return true
+		}
+		// Fewer than three digits; the letter is a regular character
+		l.escape = escapeNone
+		l.appendRune(l.unicode)
+
+	case escapeCarriageReturn:
+		l.escape = escapeNone
+		if letter == '\n' {
+			return true
 		}
-		return true
 	}
 
-	// Check if it's a valid number
-	if l.content == "-" {
+	if letter == l.quote {
+		l.flushSurrogate()
+		l.finish = true
 		return true
 	}
+	switch letter {
+	case '\\':
+		l.escape = escapeStart
+		return true
+	case '\n', '\r':
+		if !l.allowUnescapedNewlines {
+			return l.reject("escaped control character")
+		}
+	case '\t':
+		if !l.allowUnescapedNewlines && !l.ctx().json5 {
+			return l.reject("escaped control character")
+		}
+	default:
+		if letter < 0x20 && l.ctx().strict && !l.ctx().json5 {
+			return l.reject("escaped control character")
+		}
+	}
+	l.appendRune(letter)
+	return true
+}
 
-	numberRegex := regexp.MustCompile(`^-?\d+(\.\d*)?$`)
-	return numberRegex.MatchString(l.content)
+// appendRune appends a decoded character to the string
+func (l *LiteralScope) appendRune(r rune) {
+	l.flushSurrogate()
+	l.content = utf8.AppendRune(l.content, r)
 }
 
-func (l *LiteralScope) isCompletedString() bool {
-	if !strings.HasPrefix(l.content, `"`) {
-		return false
+// appendCodeUnit appends a UTF-16 code unit decoded from \uXXXX, pairing surrogates
+func (l *LiteralScope) appendCodeUnit(unit rune) {
+	switch {
+	case unit >= 0xD800 && unit < 0xDC00:
+		l.flushSurrogate()
+		l.highSurrogate = unit
+	case unit >= 0xDC00 && unit < 0xE000:
+		if l.highSurrogate != 0 {
+			combined := utf16.DecodeRune(l.highSurrogate, unit)
+			l.highSurrogate = 0
+			l.content = utf8.AppendRune(l.content, combined)
+		} else {
+			l.content = utf8.AppendRune(l.content, utf8.RuneError)
+		}
+	default:
+		l.appendRune(unit)
 	}
+}
 
-	if len(l.content) < 2 {
+// flushSurrogate replaces an unpaired high surrogate with U+FFFD, as encoding/json does
+func (l *LiteralScope) flushSurrogate() {
+	if l.highSurrogate != 0 {
+		l.highSurrogate = 0
+		l.content = utf8.AppendRune(l.content, utf8.RuneError)
+	}
+}
+
+// writeNumber advances the number state machine. A number has no closing
+// character, so the first letter that cannot continue it finishes the number
+// and is rejected for the enclosing scope to handle.
+func (l *LiteralScope) writeNumber(letter rune) bool {
+	next, ok := nextNumberState(l.number, letter, len(l.content) == 0, l.ctx().json5)
+	if !ok && l.number == numberSign && len(l.content) > 0 && l.ctx().json5 {
+		// A signed -Infinity or -NaN
+		l.kind = literalKeyword
+		return l.writeKeyword(letter)
+	}
+	if !ok {
+		l.terminateNumber()
 		return false
 	}
+	l.number = next
+	l.content = append(l.content, byte(letter))
+	return true
+}
 
-	// Check if string ends with unescaped quote
-	// Count consecutive backslashes before the final quote
-	if strings.HasSuffix(l.content, `"`) {
-		// Check how many backslashes precede the quote
-		escaped := false
-		for i := len(l.content) - 2; i >= 0; i-- {
-			if l.content[i] == '\\' {
-				escaped = !escaped
-			} else {
-				break
-			}
+// terminateNumber finishes the number at a delimiter. Strict mode rejects a
+// number that stops at a sign, decimal point or exponent marker.
+func (l *LiteralScope) terminateNumber() bool {
+	switch l.number {
+	case numberDot:
+		if l.ctx().strict && !l.ctx().json5 {
+			return l.reject("digit")
+		}
+	case numberSign, numberLeadingDot, numberExponentMark, numberExponentSign:
+		if l.ctx().strict {
+			return l.reject("digit")
+		}
+	case numberHexMark:
+		if l.ctx().strict {
+			return l.reject("hexadecimal digit")
 		}
-		// If there's an odd number of backslashes, the quote is escaped
-		return !escaped
 	}
+	l.finish = true
+	return true
+}
 
-	return false
+// nextNumberState returns the state after letter, or false if letter cannot continue the number
+// json5 enables the JSON5 extensions: a leading plus sign, leading and trailing decimal points and hexadecimal numbers.
+func nextNumberState(state numberState, letter rune, empty bool, json5 bool) (numberState, bool) {
+	switch state {
+	case numberSign:
+		if (letter == '-' || (letter == '+' && json5)) && empty {
+			return numberSign, true
+		} else if letter == '0' {
+			return numberZero, true
+		} else if isDigit(letter) {
+			return numberInteger, true
+		} else if letter == '.' && json5 {
+			return numberLeadingDot, true
+		}
+	case numberZero, numberInteger:
+		if letter == '.' {
+			return numberDot, true
+		} else if letter == 'e' || letter == 'E' {
+			return numberExponentMark, true
+		} else if isDigit(letter) && state == numberInteger {
+			return numberInteger, true
+		} else if (letter == 'x' || letter == 'X') && state == numberZero && json5 {
+			return numberHexMark, true
+		}
+	case numberLeadingDot:
+		if isDigit(letter) {
+			return numberFraction, true
+		}
+	case numberDot, numberFraction:
+		if isDigit(letter) {
+			return numberFraction, true
+		} else if (letter == 'e' || letter == 'E') && (state == numberFraction || json5) {
+			return numberExponentMark, true
+		}
+	case numberHexMark, numberHex:
+		if _, ok := hexValue(letter); ok {
+			return numberHex, true
+		}
+	case numberExponentMark:
+		if letter == '+' || letter == '-' {
+			return numberExponentSign, true
+		} else if isDigit(letter) {
+			return numberExponent, true
+		}
+	case numberExponentSign, numberExponent:
+		if isDigit(letter) {
+			return numberExponent, true
+		}
+	}
+	return state, false
 }
 
-func (l *LiteralScope) GetOrAssume() interface{} {
-	// Empty content assumes null
-	if l.content == "" {
-		return nil
+// numberLexeme returns the longest complete number in content, dropping a
+// dangling sign, decimal point or exponent marker
+func (l *LiteralScope) numberLexeme() string {
+	lexeme := string(l.content)
+	switch l.number {
+	case numberSign, numberLeadingDot:
+		return "0"
+	case numberDot, numberExponentMark, numberHexMark:
+		lexeme = lexeme[:len(lexeme)-1]
+	case numberExponentSign:
+		lexeme = lexeme[:len(lexeme)-2]
 	}
+	if l.ctx().json5 {
+		return json5NumberLexeme(lexeme)
+	}
+	return lexeme
+}
 
-	// Null values
-	if strings.HasPrefix("null", l.content) {
-		return nil
+// json5NumberLexeme rewrites a complete JSON5 number in the JSON number grammar
+func json5NumberLexeme(lexeme string) string {
+	lexeme = strings.TrimPrefix(lexeme, "+")
+	sign := ""
+	if strings.HasPrefix(lexeme, "-") {
+		sign, lexeme = "-", lexeme[1:]
+	}
+	if len(lexeme) > 2 && (lexeme[1] == 'x' || lexeme[1] == 'X') {
+		value, _ := new(big.Int).SetString(lexeme[2:], 16)
+		return sign + value.String()
+	}
+	if strings.HasPrefix(lexeme, ".") {
+		lexeme = "0" + lexeme
 	}
+	if dot := strings.IndexByte(lexeme, '.'); dot >= 0 && (dot == len(lexeme)-1 || !isDigit(rune(lexeme[dot+1]))) {
+		// Trailing decimal point
+		lexeme = lexeme[:dot] + lexeme[dot+1:]
+	}
+	return sign + lexeme
+}
 
-	// Boolean values
-	if strings.HasPrefix("true", l.content) {
-		return true
+func (l *LiteralScope) writeKeyword(letter rune) bool {
+	typed := string(l.content)
+	content := typed + string(letter)
+	keywords := l.ctx().keywords()
+	for _, kw := range keywords {
+		if strings.HasPrefix(kw.name, content) {
+			if kw.name == content {
+				l.finish = true
+			}
+			l.content = append(l.content, content[len(typed):]...)
+			return true
+		}
 	}
-	if strings.HasPrefix("false", l.content) {
+
+	if typed == "" {
 		return false
 	}
+	var candidates []string
+	for _, kw := range keywords {
+		if strings.HasPrefix(kw.name, typed) {
+			candidates = append(candidates, kw.name)
+		}
+	}
+	return l.reject(strings.Join(candidates, " or "))
+}
 
-	// String values
-	if strings.HasPrefix(l.content, `"`) {
-		return l.parseString()
+// writeIdentifier lexes an unquoted object key. Like a number it has no closing
+// character, so the first letter that cannot continue it finishes the key.
+func (l *LiteralScope) writeIdentifier(letter rune) bool {
+	if isIdentifierStart(letter) || (len(l.content) > 0 && isIdentifierPart(letter)) {
+		l.content = utf8.AppendRune(l.content, letter)
+		return true
+	}
+	if len(l.content) == 0 {
+		return l.reject("object key")
 	}
+	l.finish = true
+	return false
+}
 
-	// Number values
-	if l.content == "-" {
-		return 0
+func (l *LiteralScope) GetOrAssume() interface{} {
+	if !l.isCached() {
+		l.value = l.assume()
+		l.markCached()
 	}
+	return l.value
+}
 
-	numberRegex := regexp.MustCompile(`^-?\d+(\.\d*)?$`)
-	if numberRegex.MatchString(l.content) {
-		if num, err := strconv.ParseFloat(l.content, 64); err == nil {
-			return num
+func (l *LiteralScope) assume() interface{} {
+	switch l.kind {
+	case literalString, literalIdentifier:
+		// Incomplete escapes are not part of content yet, so the text is always valid
+		return l.text()
+
+	case literalNumber:
+		return decodeNumber(l.numberLexeme(), l.ctx())
+
+	case literalKeyword:
+		content := string(l.content)
+		for _, kw := range l.ctx().keywords() {
+			if strings.HasPrefix(kw.name, content) {
+				return kw.value
+			}
 		}
 	}
 
-	// Cannot assume
+	// Empty content assumes null
 	return nil
 }
 
-func (l *LiteralScope) parseString() interface{} {
-	jsonedString := l.content
+// text returns the decoded string without copying it. The string content is
+// only ever appended to, so bytes that are already shared are never modified.
+func (l *LiteralScope) text() string {
+	if len(l.content) == 0 {
+		return ""
+	}
+	return unsafe.String(&l.content[0], len(l.content))
+}
 
-	isCompleted := l.isCompletedString()
+// startHexEscape starts an escape of a fixed number of hexadecimal digits that encodes a code point
+func (l *LiteralScope) startHexEscape(digits int) {
+	l.escape = escapeHex
+	l.hexDigits = digits
+	l.unicode = 0
+	l.unicodeDigits = 0
+}
 
-	if !isCompleted {
-		// Remove incomplete unicode escape at the end
-		unicodeRegex := regexp.MustCompile(`\\u[\da-fA-F]{0,3}$`)
-		if unicodeRegex.MatchString(jsonedString) {
-			match := unicodeRegex.FindStringIndex(jsonedString)
-			if match != nil {
-				jsonedString = jsonedString[:match[0]]
-			}
+// writePythonEscape decodes the character following a backslash by the rules of Python string literals
+func (l *LiteralScope) writePythonEscape(letter rune) bool {
+	l.escape = escapeNone
+	switch {
+	case letter == 'u':
+		l.escape = escapeUnicode
+		l.unicode = 0
+		l.unicodeDigits = 0
+	case letter == 'x':
+		l.startHexEscape(2)
+	case letter == 'U':
+		l.startHexEscape(8)
+	case letter >= '0' && letter <= '7':
+		l.escape = escapeOctal
+		l.unicode = letter - '0'
+		l.unicodeDigits = 1
+	case letter == '\n':
+		// Line continuation
+	case letter == '\r':
+		l.escape = escapeCarriageReturn
+	case letter == 'a':
+		l.appendRune('\a')
+	case letter == 'v':
+		l.appendRune('\v')
+	case letter == '\'':
+		l.appendRune(letter)
+	case letter == '/' && l.quote == '\'':
+		// Python keeps \/ as it is; in double quotes it is the JSON escape of '/'
+		l.appendRune('\\')
+		l.appendRune(letter)
+	default:
+		decoded, ok := unescape(letter)
+		if !ok {
+			// Python keeps unknown escapes as they are
+			l.appendRune('\\')
+			decoded = letter
 		}
+		l.appendRune(decoded)
+	}
+	return true
+}
 
-		// Remove meaningless backslash at the end (but not escaped backslash)
-		for strings.HasSuffix(jsonedString, `\`) && !strings.HasSuffix(jsonedString, `\\`) {
-			jsonedString = jsonedString[:len(jsonedString)-1]
-		}
-
-		jsonedString += `"`
-	}
-
-	// If allowUnescapedNewlines is enabled, escape newlines before JSON parsing
-	if l.allowUnescapedNewlines {
-		// Carefully escape only unescaped control characters
-		result := strings.Builder{}
-		for i := 0; i < len(jsonedString); i++ {
-			char := jsonedString[i]
-			if char == '\n' || char == '\r' || char == '\t' {
-				// Check if it's already escaped by counting preceding backslashes
-				backslashes := 0
-				for j := i - 1; j >= 0; j-- {
-					if jsonedString[j] == '\\' {
-						backslashes++
-					} else {
-						break
-					}
-				}
-				// If even number of backslashes (or no backslashes), it's unescaped
-				if backslashes%2 == 0 {
-					result.WriteByte('\\')
-					switch char {
-					case '\n':
-						result.WriteByte('n')
-					case '\r':
-						result.WriteByte('r')
-					case '\t':
-						result.WriteByte('t')
-					}
-				} else {
-					result.WriteByte(char)
-				}
-			} else {
-				result.WriteByte(char)
-			}
+// unescape decodes the character following a backslash, including the escapes of the enabled dialects
+func (l *LiteralScope) unescape(letter rune) (rune, bool) {
+	if decoded, ok := unescape(letter); ok {
+		return decoded, true
+	}
+	if letter == '\'' && (l.quote == '\'' || l.ctx().json5) {
+		return letter, true
+	}
+	if l.ctx().json5 {
+		switch letter {
+		case 'v':
+			return '\v', true
+		case '0':
+			return 0, true
 		}
-		jsonedString = result.String()
-	}
-
-	var result string
-	if err := json.Unmarshal([]byte(jsonedString), &result); err != nil {
-		// Try to fix the string by removing problematic characters at the end
-		for len(jsonedString) > 2 {
-			if jsonedString[len(jsonedString)-2] == '\\' {
-				// Remove the character before the closing quote and try again
-				jsonedString = jsonedString[:len(jsonedString)-2] + `"`
-				if err := json.Unmarshal([]byte(jsonedString), &result); err == nil {
-					return result
-				}
-			} else {
-				break
-			}
+		// Any other character except a digit stands for itself
+		if !isDigit(letter) {
+			return letter, true
 		}
-		// Silently return nil for unparseable strings
-		return nil
 	}
+	return 0, false
+}
+
+// unescape decodes the character following a backslash
+func unescape(letter rune) (rune, bool) {
+	switch letter {
+	case '"', '\\', '/':
+		return letter, true
+	case 'b':
+		return '\b', true
+	case 'f':
+		return '\f', true
+	case 'n':
+		return '\n', true
+	case 'r':
+		return '\r', true
+	case 't':
+		return '\t', true
+	}
+	return 0, false
+}
+
+// hexValue returns the value of a hexadecimal digit
+func hexValue(letter rune) (rune, bool) {
+	switch {
+	case letter >= '0' && letter <= '9':
+		return letter - '0', true
+	case letter >= 'a' && letter <= 'f':
+		return letter - 'a' + 10, true
+	case letter >= 'A' && letter <= 'F':
+		return letter - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// isDigit checks if a character is an ASCII digit
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// isIdentifierStart checks if a character can start an unquoted key
+func isIdentifierStart(r rune) bool {
+	return r == '$' || r == '_' || unicode.IsLetter(r) || unicode.Is(unicode.Nl, r)
+}
 
-	return result
+// isIdentifierPart checks if a character can continue an unquoted key
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Pc) ||
+		r == '\u200C' || r == '\u200D'
 }
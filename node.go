@@ -0,0 +1,174 @@
+package incompletejson
+
+// NodeKind identifies the type of a value in the node tree
+type NodeKind int
+
+const (
+	NodeNull NodeKind = iota
+	NodeBool
+	NodeNumber
+	NodeString
+	NodeArray
+	NodeObject
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case NodeBool:
+		return "bool"
+	case NodeNumber:
+		return "number"
+	case NodeString:
+		return "string"
+	case NodeArray:
+		return "array"
+	case NodeObject:
+		return "object"
+	}
+	return "null"
+}
+
+// Node describes a value of the document together with whether it is final
+type Node struct {
+	Kind NodeKind
+	// Key is the key of the member in its parent object, or empty outside objects
+	Key  string
+	Path Path
+	// Value is the value as GetObjects returns it, including guesses for partial values
+	Value interface{}
+	// Complete reports whether the value is final
+	Complete bool
+	// KeyComplete reports whether the key is final; it is always true outside objects
+	KeyComplete bool
+	// Children holds the members of an object or the elements of an array in document order
+	Children []*Node
+}
+
+// GetNodes returns the document as a tree of nodes that tell which values are final.
+// Nodes of complete values are built once and shared between calls; like the values of
+// GetObjects, they are owned by the parser and must not be modified.
+func (p *IncompleteJsonParser) GetNodes() (*Node, error) {
+	if p.scope == nil {
+		return nil, ErrNoInput
+	}
+	return newNode(p.scope, "", baseScope(p.scope).path, p.scope.IsFinished()), nil
+}
+
+// newNode builds the node of a scope found at path under key. complete tells whether the value
+// is final, which includes values that lenient mode accepted unfinished. The path is passed down
+// from the parent because a value gathered by DuplicateKeysCollect moves into an array.
+func newNode(scope Scope, key string, path Path, complete bool) *Node {
+	base := baseScope(scope)
+	if base.node != nil && base.nodeVersion == base.version && base.node.Key == key && base.node.Path.equal(path) {
+		return base.node
+	}
+
+	node := &Node{
+		Key:         key,
+		Path:        path,
+		Value:       scope.GetOrAssume(),
+		Complete:    complete,
+		KeyComplete: true,
+	}
+	switch s := scope.(type) {
+	case *ObjectScope:
+		node.Kind = NodeObject
+		node.Children = s.childNodes(path)
+	case *ArrayScope:
+		node.Kind = NodeArray
+		node.Children = s.childNodes(path)
+	case *LiteralScope:
+		node.Kind = s.nodeKind()
+	}
+
+	// A complete value only changes again when DuplicateKeysCollect adds to it
+	if complete {
+		base.node, base.nodeVersion = node, base.version
+	}
+	return node
+}
+
+// childNodes builds the nodes of the members of an object at path
+func (o *ObjectScope) childNodes(path Path) []*Node {
+	children := make([]*Node, 0, len(o.keys)+1)
+	for _, key := range o.keys {
+		children = append(children, newNode(o.members[key], key, path.append(key), true))
+	}
+
+	// The member being written
+	if o.keyScope == nil {
+		return children
+	}
+	key := o.keyScope.GetOrAssume().(string)
+	if key == "" && !o.keyScope.IsFinished() {
+		return children
+	}
+	var node *Node
+	if o.valueScope != nil {
+		// Copy the node, which may be shared once the value is complete
+		copied := *newNode(o.valueScope, key, path.append(key), o.valueScope.IsFinished())
+		node = &copied
+	} else {
+		node = &Node{Kind: NodeNull, Key: key, Path: path.append(key)}
+	}
+	node.KeyComplete = o.keyScope.IsFinished()
+
+	value, ok := o.pendingValue(key, node.Value)
+	if !ok {
+		return children
+	}
+	node.Value = value
+	if _, exists := o.members[key]; exists {
+		// A repeated key replaces the committed member
+		for i, child := range children {
+			if child.Key == key {
+				children[i] = node
+			}
+		}
+		return children
+	}
+	return append(children, node)
+}
+
+// childNodes builds the nodes of the elements of an array at path
+func (a *ArrayScope) childNodes(path Path) []*Node {
+	var pending Scope
+	if a.state == "value" {
+		pending = a.scope
+	}
+	children := make([]*Node, 0, len(a.array))
+	for i, element := range a.array {
+		children = append(children, newNode(element, "", path.append(i), element != pending || element.IsFinished()))
+	}
+	return children
+}
+
+// nodeKind returns the kind of the value of a literal
+func (l *LiteralScope) nodeKind() NodeKind {
+	switch l.kind {
+	case literalString, literalIdentifier:
+		return NodeString
+	case literalNumber:
+		return NodeNumber
+	}
+	switch l.GetOrAssume().(type) {
+	case bool:
+		return NodeBool
+	case float64:
+		return NodeNumber
+	}
+	return NodeNull
+}
+
+// baseScope returns the common state of a scope
+func baseScope(scope Scope) *BaseScope {
+	switch s := scope.(type) {
+	case *ObjectScope:
+		return &s.BaseScope
+	case *ArrayScope:
+		return &s.BaseScope
+	case *LiteralScope:
+		return &s.BaseScope
+	}
+	return &BaseScope{}
+}
@@ -0,0 +1,78 @@
+package incompletejson
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// NumberMode selects how numbers are represented in parsed results
+type NumberMode int
+
+const (
+	// NumberFloat64 stores numbers as float64, like encoding/json (default)
+	NumberFloat64 NumberMode = iota
+	// NumberJSONNumber stores numbers as json.Number holding the original lexeme
+	NumberJSONNumber
+	// NumberInt64 stores integers that fit in 64 bits as int64 and everything else as float64
+	NumberInt64
+	// NumberBig stores integers as *big.Int and everything else as *big.Float
+	NumberBig
+)
+
+// NumberDecoder converts the lexeme of a number into the value stored in parsed results.
+// For a partial number the lexeme is its longest complete prefix, e.g. "1" for "1e-".
+type NumberDecoder func(lexeme string) interface{}
+
+// decodeNumber converts a complete number lexeme according to the parser settings
+func decodeNumber(lexeme string, context *scopeContext) interface{} {
+	if context.numberDecoder != nil {
+		return context.numberDecoder(lexeme)
+	}
+
+	switch context.numberMode {
+	case NumberJSONNumber:
+		return json.Number(lexeme)
+
+	case NumberInt64:
+		if isIntegerLexeme(lexeme) {
+			if num, err := strconv.ParseInt(lexeme, 10, 64); err == nil {
+				return num
+			}
+		}
+
+	case NumberBig:
+		if isIntegerLexeme(lexeme) {
+			if num, ok := new(big.Int).SetString(lexeme, 10); ok {
+				return num
+			}
+		}
+		// Four bits per digit keeps every decimal digit of the lexeme
+		prec := max(uint(len(lexeme))*4, 64)
+		if num, _, err := big.ParseFloat(lexeme, 10, prec, big.ToNearestEven); err == nil {
+			return num
+		}
+		return nil
+	}
+
+	// A number too large for float64 becomes ±Inf, like the result of strconv.ParseFloat
+	num, err := strconv.ParseFloat(lexeme, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return nil
+	}
+	return num
+}
+
+// isOverflow reports whether a number decoded without a NumberDecoder is out of the range of float64
+func isOverflow(value interface{}, context *scopeContext) bool {
+	num, ok := value.(float64)
+	return ok && math.IsInf(num, 0) && context.numberDecoder == nil
+}
+
+// isIntegerLexeme reports whether a number has neither a fraction nor an exponent
+func isIntegerLexeme(lexeme string) bool {
+	return !strings.ContainsAny(lexeme, ".eE")
+}
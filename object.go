@@ -1,28 +1,143 @@
 package incompletejson
 
+import "maps"
+
 // ObjectScope handles parsing of JSON objects
 type ObjectScope struct {
 	BaseScope
-	object     map[string]interface{}
-	state      string // "key", "colons", "value", "comma"
+	keys       []string         // member keys in arrival order
+	members    map[string]Scope // committed members
+	state      string           // "key", "colons", "value", "comma"
 	keyScope   *LiteralScope
 	valueScope Scope
+	opened     bool
+	collected  map[string]bool // keys whose values are gathered by DuplicateKeysCollect
+	// repeated lists the keys committed again in arrival order, which replaced or
+	// extended their member; with keys it lets readers follow every commit with counters
+	repeated []string
+
+	// snapshot is updated in place, or in a copy of the previous one with WithImmutableSnapshots:
+	// only members committed since the last snapshot and the in-progress member are rebuilt,
+	// and the other members are shared
+	snapshot      objectSnapshot
+	snapshotValue interface{}
+	changed       []string
+	pendingKey    string
+	hasPendingKey bool
 }
 
 func NewObjectScope() *ObjectScope {
 	return &ObjectScope{
-		object: make(map[string]interface{}),
-		state:  "key",
+		members: make(map[string]Scope),
+		state:   "key",
+	}
+}
+
+// currentKey returns the key of the member being parsed
+func (o *ObjectScope) currentKey() string {
+	return o.keyScope.GetOrAssume().(string)
+}
+
+// memberCursor marks how far a reader has followed the commits of an object
+type memberCursor struct {
+	keys     int // leading entries of keys
+	repeated int // leading entries of repeated
+}
+
+// cursor returns a cursor past every commit so far
+func (o *ObjectScope) cursor() memberCursor {
+	return memberCursor{keys: len(o.keys), repeated: len(o.repeated)}
+}
+
+// committedSince returns the keys committed after the cursor: new keys first, then repeated ones
+func (o *ObjectScope) committedSince(c memberCursor) []string {
+	keys := o.keys[c.keys:]
+	if c.repeated < len(o.repeated) {
+		keys = append(keys[:len(keys):len(keys)], o.repeated[c.repeated:]...)
+	}
+	return keys
+}
+
+// commit stores a member value once its key and value are known, applying the duplicate key policy
+func (o *ObjectScope) commit() {
+	key := o.currentKey()
+	value := o.valueScope
+	o.keyScope = nil
+	o.valueScope = nil
+	o.ctx().complete(value)
+
+	existing, exists := o.members[key]
+	if !exists {
+		o.keys = append(o.keys, key)
+		o.members[key] = value
+	} else {
+		switch o.ctx().duplicateKeys {
+		case DuplicateKeysFirstWins:
+			return
+		case DuplicateKeysCollect:
+			o.members[key] = o.collect(key, existing, value)
+		default:
+			o.members[key] = value
+		}
+		o.repeated = append(o.repeated, key)
 	}
+	o.changed = append(o.changed, key)
+}
+
+// collect gathers all values of a repeated key into an array
+func (o *ObjectScope) collect(key string, existing Scope, value Scope) Scope {
+	collected, ok := existing.(*ArrayScope)
+	if !ok || !o.collected[key] {
+		collected = NewArrayScope()
+		collected.allowUnescapedNewlines = o.allowUnescapedNewlines
+		collected.context = o.context
+		collected.path = o.path.append(key)
+		collected.opened = true
+		collected.state = "comma"
+		collected.finish = true
+		collected.array = append(collected.array, existing)
+		if o.collected == nil {
+			o.collected = make(map[string]bool)
+		}
+		o.collected[key] = true
+	}
+	collected.array = append(collected.array, value)
+	collected.touch()
+	return collected
+}
+
+// pendingValue returns how the in-progress member appears in a snapshot under the duplicate key policy.
+// ok is false when the member must not override a committed value.
+func (o *ObjectScope) pendingValue(key string, value interface{}) (interface{}, bool) {
+	existing, exists := o.members[key]
+	if !exists {
+		return value, true
+	}
+	switch o.ctx().duplicateKeys {
+	case DuplicateKeysFirstWins:
+		return nil, false
+	case DuplicateKeysCollect:
+		var values []interface{}
+		if o.collected[key] {
+			values = append(values, existing.GetOrAssume().([]interface{})...)
+		} else {
+			values = append(values, existing.GetOrAssume())
+		}
+		return append(values, value), true
+	}
+	return value, true
 }
 
 func (o *ObjectScope) Write(letter rune) bool {
 	if o.finish {
 		return false
 	}
+	o.touch()
 
 	// Ignore first {
-	if len(o.object) == 0 && o.state == "key" && o.keyScope == nil && o.valueScope == nil {
+	if !o.opened {
+		o.opened = true
+		o.ctx().begin(o)
 		if letter == '{' {
 			return true
 		}
@@ -31,103 +146,108 @@ func (o *ObjectScope) Write(letter rune) bool {
 	switch o.state {
 	case "key":
 		if o.keyScope == nil {
-			if isWhitespace(letter) {
+			if o.isWhitespace(letter) {
 				return true
 			} else if letter == '}' {
+				if len(o.keys) > 0 && o.ctx().strict && !o.ctx().trailingCommas {
+					// Trailing comma
+					return o.reject("object key")
+				}
 				// Empty object case: {}
 				o.finish = true
 				return true
-			} else if letter == '"' {
-				o.keyScope = NewLiteralScope()
-				o.keyScope.SetAllowUnescapedNewlines(o.allowUnescapedNewlines)
+			} else if letter == '"' || (letter == '\'' && o.ctx().singleQuotes) {
+				o.keyScope = o.newKeyScope()
+				return o.keyScope.Write(letter)
+			} else if o.ctx().unquotedKeys && isIdentifierStart(letter) {
+				o.keyScope = o.newKeyScope()
+				o.keyScope.kind = literalIdentifier
 				return o.keyScope.Write(letter)
 			} else {
-				return false
+				return o.reject("object key or '}'")
 			}
 		} else {
-			o.keyScope.Write(letter)
-			key := o.keyScope.GetOrAssume()
-			if _, ok := key.(string); ok {
-				if o.keyScope.IsFinished() {
-					o.state = "colons"
-				}
-				return true
-			} else {
+			success := o.keyScope.Write(letter)
+			if !success && !o.keyScope.IsFinished() && (o.ctx().strict || !o.isWhitespace(letter)) {
 				return false
 			}
+			if o.keyScope.IsFinished() {
+				o.state = "colons"
+				key := o.currentKey()
+				o.ctx().key(o.path.append(key), key)
+				if _, exists := o.members[key]; exists && o.ctx().duplicateKeys == DuplicateKeysError {
+					return o.fail(&DuplicateKeyError{Key: key, Path: o.path.append(key)})
+				}
+				if !success {
+					// An unquoted key ends at the first character it does not consume
+					return o.Write(letter)
+				}
+			}
+			return true
 		}
 
 	case "colons":
-		if isWhitespace(letter) {
+		if o.isWhitespace(letter) {
 			return true
 		} else if letter == ':' {
 			o.state = "value"
 			o.valueScope = nil
 			return true
 		} else {
-			return false
+			return o.reject("':'")
 		}
 
 	case "value":
 		if o.valueScope == nil {
-			if isWhitespace(letter) {
+			if o.isWhitespace(letter) {
 				return true
-			} else if letter == '{' {
-				o.valueScope = NewObjectScope()
-				o.valueScope.SetAllowUnescapedNewlines(o.allowUnescapedNewlines)
-				return o.valueScope.Write(letter)
-			} else if letter == '[' {
-				o.valueScope = NewArrayScope()
-				o.valueScope.SetAllowUnescapedNewlines(o.allowUnescapedNewlines)
-				return o.valueScope.Write(letter)
 			} else {
-				o.valueScope = NewLiteralScope()
-				o.valueScope.SetAllowUnescapedNewlines(o.allowUnescapedNewlines)
+				o.valueScope = o.newChildScope(letter, o.currentKey())
 				return o.valueScope.Write(letter)
 			}
 		} else {
 			success := o.valueScope.Write(letter)
 			if o.valueScope.IsFinished() {
-				key := o.keyScope.GetOrAssume().(string)
-				o.object[key] = o.valueScope.GetOrAssume()
+				o.commit()
 				o.state = "comma"
+				if !success {
+					// The value ended at a delimiter it did not consume (numbers)
+					return o.Write(letter)
+				}
 				return true
 			} else if success {
 				return true
+			} else if o.ctx().strict {
+				// Only lenient mode accepts an unfinished value
+				return false
 			} else {
-				if isWhitespace(letter) {
+				if o.isWhitespace(letter) {
 					return true
 				} else if letter == ',' {
-					key := o.keyScope.GetOrAssume().(string)
-					o.object[key] = o.valueScope.GetOrAssume()
+					o.commit()
 					o.state = "key"
-					o.keyScope = nil
-					o.valueScope = nil
 					return true
 				} else if letter == '}' {
-					key := o.keyScope.GetOrAssume().(string)
-					o.object[key] = o.valueScope.GetOrAssume()
+					o.commit()
 					o.finish = true
 					return true
 				} else {
-					return false
+					return o.reject("',' or '}'")
 				}
 			}
 		}
 
 	case "comma":
-		if isWhitespace(letter) {
+		if o.isWhitespace(letter) {
 			return true
 		} else if letter == ',' {
 			o.state = "key"
-			o.keyScope = nil
-			o.valueScope = nil
 			return true
 		} else if letter == '}' {
 			o.finish = true
 			return true
 		} else {
-			return false
+			return o.reject("',' or '}'")
 		}
 	}
 
@@ -135,30 +255,78 @@ func (o *ObjectScope) Write(letter rune) bool {
 }
 
 func (o *ObjectScope) GetOrAssume() interface{} {
-	result := make(map[string]interface{})
+	if o.snapshot != nil && o.isCached() {
+		return o.snapshotValue
+	}
+	if o.snapshot == nil {
+		if o.ctx().orderedObjects {
+			ordered := NewOrderedObject()
+			o.snapshot, o.snapshotValue = ordered, ordered
+		} else {
+			object := make(map[string]interface{}, len(o.keys))
+			o.snapshot, o.snapshotValue = mapSnapshot(object), object
+		}
+	} else if o.ctx().immutableSnapshots {
+		// Leave the snapshots already returned untouched
+		switch previous := o.snapshotValue.(type) {
+		case *OrderedObject:
+			ordered := previous.clone()
+			o.snapshot, o.snapshotValue = ordered, ordered
+		case map[string]interface{}:
+			object := maps.Clone(previous)
+			o.snapshot, o.snapshotValue = mapSnapshot(object), object
+		}
+	}
 
-	// Copy existing completed key-value pairs
-	for k, v := range o.object {
-		result[k] = v
+	// Withdraw the incomplete member shown by the previous snapshot; its key may have grown since
+	if o.hasPendingKey {
+		if member, ok := o.members[o.pendingKey]; ok {
+			o.snapshot.Set(o.pendingKey, member.GetOrAssume())
+		} else {
+			o.snapshot.Delete(o.pendingKey)
+		}
+		o.hasPendingKey = false
+	}
+
+	// Add members completed since the previous snapshot
+	for _, key := range o.changed {
+		o.snapshot.Set(key, o.members[key].GetOrAssume())
 	}
+	o.changed = o.changed[:0]
 
 	// Handle incomplete key-value pair
-	if o.keyScope != nil || o.valueScope != nil {
-		if o.keyScope != nil {
-			key := o.keyScope.GetOrAssume()
-			if keyStr, ok := key.(string); ok && len(keyStr) > 0 {
-				var value interface{}
-				if o.valueScope != nil {
-					value = o.valueScope.GetOrAssume()
-				}
-				if value != nil {
-					result[keyStr] = value
-				} else {
-					result[keyStr] = nil
-				}
+	if o.keyScope != nil {
+		key := o.keyScope.GetOrAssume()
+		if keyStr, ok := key.(string); ok && len(keyStr) > 0 {
+			var value interface{}
+			if o.valueScope != nil {
+				value = o.valueScope.GetOrAssume()
+			}
+			if value, ok := o.pendingValue(keyStr, value); ok {
+				o.snapshot.Set(keyStr, value)
+				o.pendingKey = keyStr
+				o.hasPendingKey = true
 			}
 		}
 	}
 
-	return result
+	o.markCached()
+	return o.snapshotValue
+}
+
+// objectSnapshot is the value an ObjectScope builds: a plain map or an *OrderedObject
+type objectSnapshot interface {
+	Set(key string, value interface{})
+	Delete(key string)
+}
+
+// mapSnapshot adapts a plain map to objectSnapshot
+type mapSnapshot map[string]interface{}
+
+func (m mapSnapshot) Set(key string, value interface{}) {
+	m[key] = value
+}
+
+func (m mapSnapshot) Delete(key string) {
+	delete(m, key)
 }
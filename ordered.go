@@ -0,0 +1,65 @@
+package incompletejson
+
+import "maps"
+
+// OrderedObject is a JSON object that keeps its keys in the order they arrived.
+// Parsers created with WithOrderedObjects(true) return objects as *OrderedObject.
+type OrderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewOrderedObject creates an empty ordered object
+func NewOrderedObject() *OrderedObject {
+	return &OrderedObject{values: make(map[string]interface{})}
+}
+
+// Keys returns the keys in arrival order
+func (o *OrderedObject) Keys() []string {
+	return append([]string(nil), o.keys...)
+}
+
+// Get returns the value stored under key
+func (o *OrderedObject) Get(key string) (interface{}, bool) {
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// Len returns the number of keys
+func (o *OrderedObject) Len() int {
+	return len(o.keys)
+}
+
+// Set stores value under key. A new key is appended; an existing key keeps its position.
+func (o *OrderedObject) Set(key string, value interface{}) {
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// clone returns a copy of the object that shares its values
+func (o *OrderedObject) clone() *OrderedObject {
+	return &OrderedObject{keys: append([]string(nil), o.keys...), values: maps.Clone(o.values)}
+}
+
+// Delete removes key
+func (o *OrderedObject) Delete(key string) {
+	if _, exists := o.values[key]; !exists {
+		return
+	}
+	delete(o.values, key)
+
+	// The most recently added key is the common case
+	for i := len(o.keys) - 1; i >= 0; i-- {
+		if o.keys[i] == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+// MarshalJSON encodes the object with its keys in arrival order
+func (o *OrderedObject) MarshalJSON() ([]byte, error) {
+	return marshalSnapshot(o)
+}
@@ -1,11 +1,11 @@
 package incompletejson
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
+	"unicode/utf8"
 )
 
 // IncompleteJsonParser is the main parser struct
@@ -15,6 +15,43 @@ type IncompleteJsonParser struct {
 	ignoreExtraCharacters  bool
 	allowUnescapedNewlines bool
 	validateRequiredFields bool
+	onSkippedValue         func(SkippedValue)
+	scopeContext           scopeContext
+	// pending holds the leading bytes of a UTF-8 sequence split across chunks
+	pending        []byte
+	position       position
+	comment        commentState
+	snapshotPolicy SnapshotPolicy
+	patchShadow    *patchShadow
+	patchSeq       uint64
+	binding        *binding
+	// end is the byte offset just past the document once it is complete
+	end int64
+	// remainder holds the text written after the end of the document
+	remainder []byte
+
+	// Multi-document mode
+	multipleDocuments bool
+	ndjson            bool
+	onDocument        func(Document)
+	documentIndex     int
+	documentOffset    int64
+	skipping          bool // discarding a malformed document up to the end of its line
+	lineEnded         bool // a line feed has followed the finished NDJSON document
+
+	// Embedded JSON mode
+	extract      bool
+	extractState extractState
+	backticks    int // length of the current run of backticks
+	fenceInfo    strings.Builder
+
+	// Schema validation
+	schema          *schema
+	schemaErr       error // the schema could not be compiled
+	schemaValidator schemaValidator
+
+	// err is a fatal error that stops the parser until Reset
+	err error
 }
 
 // ParserOption defines a function type for parser options
@@ -41,6 +78,125 @@ func WithRequiredFields(validate bool) ParserOption {
 	}
 }
 
+// WithUseNumber sets the option to return numbers as json.Number holding the original lexeme
+func WithUseNumber(use bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		if use {
+			p.scopeContext.numberMode = NumberJSONNumber
+		} else {
+			p.scopeContext.numberMode = NumberFloat64
+		}
+	}
+}
+
+// WithNumberMode sets how numbers are represented in parsed results
+func WithNumberMode(mode NumberMode) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.numberMode = mode
+	}
+}
+
+// WithNumberDecoder sets a function that converts number lexemes, overriding the number mode
+func WithNumberDecoder(decoder NumberDecoder) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.numberDecoder = decoder
+	}
+}
+
+// WithOrderedObjects sets the option to return objects as *OrderedObject, keeping the order of their keys
+func WithOrderedObjects(ordered bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.orderedObjects = ordered
+	}
+}
+
+// WithImmutableSnapshots sets the option to rebuild changed objects and arrays in copies instead of
+// updating the previous snapshot in place, so that a snapshot returned by GetObjects never changes and
+// can be kept or read while Write runs. Every snapshot then copies the objects and arrays on the path
+// that changed, which costs in proportion to their size rather than to the size of the change.
+func WithImmutableSnapshots(immutable bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.immutableSnapshots = immutable
+	}
+}
+
+// WithDuplicateKeys sets how repeated object keys are handled
+func WithDuplicateKeys(policy DuplicateKeyPolicy) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.duplicateKeys = policy
+	}
+}
+
+// WithStrict sets the option to accept only RFC 8259 JSON, rejecting everything lenient mode tolerates:
+// non-ASCII whitespace, raw control characters in strings, trailing commas, incomplete values followed
+// by a delimiter, and characters that an inner value rejected
+func WithStrict(strict bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.strict = strict
+	}
+}
+
+// WithComments sets the option to skip // line comments and /* */ block comments between tokens
+func WithComments(allow bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.comments = allow
+	}
+}
+
+// WithSingleQuotes sets the option to accept strings and keys enclosed in single quotes
+func WithSingleQuotes(allow bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.singleQuotes = allow
+	}
+}
+
+// WithUnquotedKeys sets the option to accept object keys written as bare identifiers
+func WithUnquotedKeys(allow bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.unquotedKeys = allow
+	}
+}
+
+// WithTrailingCommas sets the option to accept a comma after the last member or element in strict mode.
+// Lenient mode always accepts trailing commas.
+func WithTrailingCommas(allow bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.trailingCommas = allow
+	}
+}
+
+// WithJSON5 sets the option to accept JSON5: comments, single quotes, unquoted keys and trailing commas,
+// as well as hexadecimal numbers, leading and trailing decimal points, a leading plus sign,
+// Infinity and NaN, additional string escapes and line continuations
+func WithJSON5(allow bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.json5 = allow
+		p.scopeContext.comments = allow
+		p.scopeContext.singleQuotes = allow
+		p.scopeContext.unquotedKeys = allow
+		p.scopeContext.trailingCommas = allow
+	}
+}
+
+// WithPythonLiterals sets the option to accept Python literal syntax as produced by repr():
+// True, False and None, single-quoted strings with Python escape rules, tuples parsed as arrays
+// and trailing commas
+func WithPythonLiterals(allow bool) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.pythonLiterals = allow
+		p.scopeContext.singleQuotes = allow
+		p.scopeContext.trailingCommas = allow
+	}
+}
+
+// WithOnValueComplete sets a function called with the path and value of every value as soon as it is final.
+// Values are reported in the order they end, so the members of a container come before the container.
+func WithOnValueComplete(fn func(path Path, value interface{})) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.scopeContext.onValueComplete = fn
+	}
+}
+
 // NewIncompleteJsonParser creates a new parser instance with optional configuration
 func NewIncompleteJsonParser(options ...ParserOption) *IncompleteJsonParser {
 	parser := &IncompleteJsonParser{}
@@ -56,7 +212,27 @@ func NewIncompleteJsonParser(options ...ParserOption) *IncompleteJsonParser {
 // Parse is a static method that parses JSON in a single step
 func Parse(chunk string, options ...ParserOption) (interface{}, error) {
 	parser := NewIncompleteJsonParser(options...)
-	err := parser.Write(chunk)
+	_, err := parser.WriteString(chunk)
+	if err != nil {
+		return nil, err
+	}
+	return parser.GetObjects()
+}
+
+// ParseBytes is a static method that parses JSON bytes in a single step
+func ParseBytes(data []byte, options ...ParserOption) (interface{}, error) {
+	parser := NewIncompleteJsonParser(options...)
+	_, err := parser.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	return parser.GetObjects()
+}
+
+// ParseReader is a static method that reads r until EOF and parses everything it yields
+func ParseReader(r io.Reader, options ...ParserOption) (interface{}, error) {
+	parser := NewIncompleteJsonParser(options...)
+	_, err := parser.ReadFrom(r)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +242,7 @@ func Parse(chunk string, options ...ParserOption) (interface{}, error) {
 // UnmarshalTo is a static method that parses JSON and stores the result in the value pointed to by v
 func UnmarshalTo(chunk string, v interface{}, options ...ParserOption) error {
 	parser := NewIncompleteJsonParser(options...)
-	err := parser.Write(chunk)
+	_, err := parser.WriteString(chunk)
 	if err != nil {
 		return err
 	}
@@ -75,90 +251,318 @@ func UnmarshalTo(chunk string, v interface{}, options ...ParserOption) error {
 
 // Reset resets the parser's internal state
 func (p *IncompleteJsonParser) Reset() {
+	p.resetDocument()
+	p.pending = nil
+	p.position = position{}
+	p.documentIndex = 0
+	p.skipping = false
+	p.extractState = extractProse
+	p.backticks = 0
+	p.patchShadow = nil
+	// ignoreExtraCharacters設定は保持する
+}
+
+// resetDocument clears the state of the current document
+func (p *IncompleteJsonParser) resetDocument() {
 	p.scope = nil
 	p.finish = false
-	// ignoreExtraCharacters設定は保持する
+	p.comment = commentNone
+	p.err = nil
+	p.scopeContext.err = nil
+	p.end = 0
+	p.remainder = nil
+	p.lineEnded = false
+	p.schemaValidator = schemaValidator{}
 }
 
-// Write processes a chunk of JSON data
-func (p *IncompleteJsonParser) Write(chunk string) error {
-	for _, letter := range chunk {
-		if p.finish {
-			if p.ignoreExtraCharacters {
-				// オプションが有効な場合は余分な文字を無視
-				continue
+// Write processes a chunk of JSON data and implements io.Writer.
+// A UTF-8 sequence cut off at the end of the chunk is kept and completed by the next call.
+// On error, n is the number of bytes consumed before the offending character.
+// Write took a string before it implemented io.Writer; use WriteString to write strings.
+func (p *IncompleteJsonParser) Write(chunk []byte) (n int, err error) {
+	if p.schemaErr != nil {
+		return 0, p.schemaErr
+	}
+	data := chunk
+	carried := len(p.pending)
+	if carried > 0 {
+		data = append(p.pending, chunk...)
+		p.pending = nil
+	}
+
+	offset := 0
+	for offset < len(data) {
+		if !utf8.FullRune(data[offset:]) {
+			// Keep the partial sequence for the next chunk
+			p.pending = append([]byte(nil), data[offset:]...)
+			break
+		}
+		letter, size := utf8.DecodeRune(data[offset:])
+		if err := p.writeRune(letter); err != nil {
+			return max(offset-carried, 0), err
+		}
+		p.position.advance(letter, size)
+		offset += size
+	}
+	p.flushEvents()
+	if err := p.checkSchema(); err != nil && !p.multipleDocuments && !p.extract {
+		// Streams report the violation with the document
+		return len(chunk), err
+	}
+	if err := p.updateBinding(); err != nil {
+		return len(chunk), err
+	}
+	return len(chunk), nil
+}
+
+// WriteString processes a chunk of JSON data given as a string and implements io.StringWriter
+func (p *IncompleteJsonParser) WriteString(chunk string) (n int, err error) {
+	return p.Write([]byte(chunk))
+}
+
+// ReadFrom reads r until EOF, writing everything it yields, and implements io.ReaderFrom
+func (p *IncompleteJsonParser) ReadFrom(r io.Reader) (n int64, err error) {
+	buf := make([]byte, 32*1024)
+	for {
+		read, readErr := r.Read(buf)
+		if read > 0 {
+			written, err := p.Write(buf[:read])
+			n += int64(written)
+			if err != nil {
+				return n, err
 			}
-			// デフォルトの動作：空白文字のみ許可
-			if isWhitespace(letter) {
-				continue
+		}
+		if readErr == io.EOF {
+			return n, nil
+		}
+		if readErr != nil {
+			return n, readErr
+		}
+	}
+}
+
+// writeRune feeds a single character to the scopes
+func (p *IncompleteJsonParser) writeRune(letter rune) error {
+	if p.extract {
+		return p.writeExtractRune(letter)
+	}
+	if p.multipleDocuments {
+		return p.writeStreamRune(letter)
+	}
+	return p.writeDocumentRune(letter)
+}
+
+// writeDocumentRune feeds a single character to the current document
+func (p *IncompleteJsonParser) writeDocumentRune(letter rune) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.scopeContext.rejected = false
+
+	if p.finish && p.ignoreExtraCharacters {
+		// オプションが有効な場合は余分な文字を無視
+		p.remainder = utf8.AppendRune(p.remainder, letter)
+		return nil
+	}
+	if p.scopeContext.comments {
+		finished := p.finish
+		if comment, err := p.writeComment(letter); comment {
+			if finished && err == nil {
+				// A comment after the document is part of the trailing text
+				p.remainder = utf8.AppendRune(p.remainder, letter)
 			}
-			return errors.New("parser is already finished")
-		}
-
-		if p.scope == nil {
-			if isWhitespace(letter) {
-				continue
-			} else if letter == '{' {
-				p.scope = NewObjectScope()
-			} else if letter == '[' {
-				p.scope = NewArrayScope()
-			} else {
-				p.scope = NewLiteralScope()
+			return err
+		}
+	}
+	return p.writeToken(letter)
+}
+
+// writeToken feeds a character outside of comments to the scopes
+func (p *IncompleteJsonParser) writeToken(letter rune) error {
+	if p.finish {
+		// デフォルトの動作：空白文字のみ許可
+		if p.ignoreExtraCharacters || p.isWhitespace(letter) {
+			p.remainder = utf8.AppendRune(p.remainder, letter)
+			return nil
+		}
+		return p.newParseError(letter, ErrAlreadyFinished)
+	}
+
+	if p.scope == nil {
+		if p.isWhitespace(letter) {
+			return nil
+		}
+		p.scope = newScope(letter, p.allowUnescapedNewlines, &p.scopeContext, Path{})
+		success := p.scope.Write(letter)
+		if !success {
+			return p.newParseError(letter, nil)
+		}
+	} else {
+		success := p.scope.Write(letter)
+		if p.scopeContext.err != nil {
+			// Fatal errors stop the parser even where a scope tolerated the character
+			p.err = p.newParseError(letter, p.scopeContext.err)
+			return p.err
+		}
+		if success {
+			if p.scope.IsFinished() {
+				return p.finishDocument(p.position.offset + int64(utf8.RuneLen(letter)))
 			}
-			p.scope.SetAllowUnescapedNewlines(p.allowUnescapedNewlines)
-			success := p.scope.Write(letter)
-			if !success {
-				return errors.New("failed to parse the JSON string")
+		} else if p.scope.IsFinished() && (p.isWhitespace(letter) || p.ignoreExtraCharacters || p.multipleDocuments) {
+			// A top-level number ends at whitespace, or at the start of whatever follows
+			// the document when that is allowed; other characters are errors, as in 42x
+			if err := p.finishDocument(p.position.offset); err != nil {
+				return err
 			}
+			return p.writeToken(letter)
+		} else if p.scope.IsFinished() {
+			baseScope(p.scope).reject("whitespace or end of input")
+			return p.newParseError(letter, nil)
 		} else {
-			success := p.scope.Write(letter)
-			if success {
-				if p.scope.IsFinished() {
-					p.finish = true
-					continue
-				}
-			} else {
-				return errors.New("failed to parse the JSON string")
-			}
+			return p.newParseError(letter, nil)
 		}
 	}
 	return nil
 }
 
-// GetObjects returns the parsed JavaScript object
+// finishDocument records that the document is complete, ending at the given byte offset,
+// and checks the complete document against the schema
+func (p *IncompleteJsonParser) finishDocument(end int64) error {
+	p.finish = true
+	p.end = end
+	p.scopeContext.complete(p.scope)
+	return p.checkSchema()
+}
+
+// Close tells the parser that the input has ended and implements io.Closer.
+// A number at the end of the input is completed; if the document is still
+// incomplete, a *ParseError wrapping ErrUnexpectedEnd is returned. The snapshot
+// of the partial document remains available either way.
+func (p *IncompleteJsonParser) Close() error {
+	err := p.close()
+	if bindErr := p.updateBinding(); err == nil {
+		err = bindErr
+	}
+	return err
+}
+
+func (p *IncompleteJsonParser) close() error {
+	if len(p.pending) > 0 {
+		// A truncated UTF-8 sequence can no longer be completed
+		size := len(p.pending)
+		p.pending = nil
+		if err := p.writeRune(utf8.RuneError); err != nil {
+			return err
+		}
+		p.position.advance(utf8.RuneError, size)
+	}
+	if p.extract {
+		return p.closeExtract()
+	}
+	if p.multipleDocuments {
+		return p.closeStream()
+	}
+	return p.endDocument()
+}
+
+// endDocument completes the current document at the end of its input
+func (p *IncompleteJsonParser) endDocument() error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.inComment() {
+		// The input ends inside a block comment or after a lone slash
+		return p.newParseError(0, ErrUnexpectedEnd)
+	}
+	if p.finish {
+		return nil
+	}
+	p.scopeContext.rejected = false
+	if literal, ok := p.scope.(*LiteralScope); ok && literal.kind == literalNumber && literal.terminateNumber() {
+		return p.finishDocument(p.position.offset)
+	}
+	return p.newParseError(0, ErrUnexpectedEnd)
+}
+
+// isWhitespace checks if a character is whitespace around the document
+func (p *IncompleteJsonParser) isWhitespace(r rune) bool {
+	return p.scopeContext.isWhitespace(r)
+}
+
+// newParseError describes a failure at the current character
+func (p *IncompleteJsonParser) newParseError(letter rune, cause error) *ParseError {
+	err := &ParseError{
+		Offset:     p.position.offset,
+		RuneOffset: p.position.runeOffset,
+		Line:       p.position.line + 1,
+		Column:     p.position.column + 1,
+		Path:       Path{},
+		Char:       letter,
+		Err:        cause,
+	}
+	if p.scopeContext.rejected {
+		err.Expected = p.scopeContext.expected
+		err.Path = p.scopeContext.rejectedPath
+	}
+	return err
+}
+
+// Remainder returns the text written after the end of the document. With WithIgnoreExtraCharacters(true)
+// this is any trailing text, such as an explanation following the JSON; otherwise only whitespace can
+// follow the document.
+func (p *IncompleteJsonParser) Remainder() string {
+	return string(p.remainder)
+}
+
+// EndOffset returns the byte offset just past the last character of the document, counted from the
+// start of the input across all chunks. ok is false while the document is incomplete.
+func (p *IncompleteJsonParser) EndOffset() (offset int64, ok bool) {
+	return p.end, p.finish
+}
+
+// GetObjects returns the parsed JavaScript object.
+// Snapshots are cached, so only the values written since the previous call are rebuilt.
+// The objects and arrays that changed are updated in place, so a returned snapshot changes
+// on later calls and must not be read while GetObjects runs, and the values that did not
+// change are shared between snapshots, so the maps and slices must not be modified.
+// WithImmutableSnapshots rebuilds changed values in copies instead.
+// With WithSnapshotPolicy(SnapshotCompleted) it returns the same as GetCompleted.
 func (p *IncompleteJsonParser) GetObjects() (interface{}, error) {
+	if p.snapshotPolicy == SnapshotCompleted {
+		return p.GetCompleted()
+	}
 	if p.scope != nil {
 		return p.scope.GetOrAssume(), nil
 	}
-	return nil, errors.New("no input to parse")
+	return nil, ErrNoInput
 }
 
-// UnmarshalTo parses the JSON data and stores the result in the value pointed to by v
+// UnmarshalTo parses the JSON data and stores the result in the value pointed to by v.
+// The parsed values are decoded straight into v following the rules of encoding/json,
+// without building the snapshot of GetObjects.
 func (p *IncompleteJsonParser) UnmarshalTo(v interface{}) error {
-	result, err := p.GetObjects()
-	if err != nil {
-		return err
-	}
-
-	// If result is nil (null JSON), return an error for type safety
-	if result == nil {
-		return errors.New("cannot unmarshal null into struct")
+	if p.scope == nil {
+		return ErrNoInput
 	}
-
-	// Convert to JSON bytes and then unmarshal to the target type
-	jsonBytes, err := json.Marshal(result)
-	if err != nil {
-		return err
+	root := rootNode(p.scope)
+	if literal, ok := p.scope.(*LiteralScope); ok {
+		if p.snapshotPolicy == SnapshotCompleted && !root.complete {
+			return ErrNoInput
+		}
+		// If the document is null, return an error for type safety
+		if literal.nodeKind() == NodeNull {
+			return ErrNullValue
+		}
 	}
 
-	err = json.Unmarshal(jsonBytes, v)
+	err := p.decodeNode(root, v)
 	if err != nil {
 		return err
 	}
 
 	// Validate required fields if option is enabled
 	if p.validateRequiredFields {
-		return p.validateRequired(v, result)
+		return p.validateRequired(v)
 	}
 
 	return nil
@@ -179,7 +583,7 @@ func ParseAs[T any](chunk string, options ...ParserOption) (T, error) {
 }
 
 // validateRequired checks that all non-omitempty fields are present in the JSON
-func (p *IncompleteJsonParser) validateRequired(target interface{}, jsonData interface{}) error {
+func (p *IncompleteJsonParser) validateRequired(target interface{}) error {
 	targetValue := reflect.ValueOf(target)
 	if targetValue.Kind() == reflect.Ptr {
 		targetValue = targetValue.Elem()
@@ -191,7 +595,7 @@ func (p *IncompleteJsonParser) validateRequired(target interface{}, jsonData int
 	}
 
 	targetType := targetValue.Type()
-	jsonMap, ok := jsonData.(map[string]interface{})
+	object, ok := p.scope.(*ObjectScope)
 	if !ok {
 		return fmt.Errorf("expected JSON object for struct type %s", targetType.Name())
 	}
@@ -219,10 +623,8 @@ func (p *IncompleteJsonParser) validateRequired(target interface{}, jsonData int
 		}
 
 		// If field doesn't have omitempty and is not present in JSON, it's an error
-		if !hasOmitEmpty {
-			if _, exists := jsonMap[fieldName]; !exists {
-				missingFields = append(missingFields, fieldName)
-			}
+		if !hasOmitEmpty && !p.hasMember(object, fieldName) {
+			missingFields = append(missingFields, fieldName)
 		}
 	}
 
@@ -232,3 +634,18 @@ func (p *IncompleteJsonParser) validateRequired(target interface{}, jsonData int
 
 	return nil
 }
+
+// hasMember reports whether the snapshot of an object includes key
+func (p *IncompleteJsonParser) hasMember(object *ObjectScope, key string) bool {
+	if _, ok := object.members[key]; ok {
+		return true
+	}
+	if object.keyScope == nil || object.currentKey() != key {
+		return false
+	}
+	if p.snapshotPolicy == SnapshotCompleted {
+		_, ok := completedValue(object.valueScope)
+		return object.keyScope.IsFinished() && ok
+	}
+	return true
+}
@@ -2,7 +2,19 @@ package incompletejson
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/require"
 )
@@ -11,7 +23,7 @@ func TestIncompleteJsonParser_CompleteObjects(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","age":30,"city":"New York"}`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -30,7 +42,7 @@ func TestIncompleteJsonParser_IncompleteObjects(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","age":30,"city":"New York"`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -49,7 +61,7 @@ func TestIncompleteJsonParser_IncompleteArrays(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `["apple","banana","orange"`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -64,7 +76,7 @@ func TestIncompleteJsonParser_IncompleteStrings(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","message":"Hello, world!`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -83,10 +95,10 @@ func TestIncompleteJsonParser_MultipleChunks(t *testing.T) {
 	chunk1 := `{"name":"John","a`
 	chunk2 := `ge":30,"city":"New York"}`
 
-	err := parser.Write(chunk1)
+	_, err := parser.WriteString(chunk1)
 	require.NoError(t, err)
 
-	err = parser.Write(chunk2)
+	_, err = parser.WriteString(chunk2)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -109,7 +121,7 @@ func TestIncompleteJsonParser_NullValues(t *testing.T) {
 			parser := NewIncompleteJsonParser()
 			jsonString := `{"name":"John","age":30,"isStudent":` + nullValue
 
-			err := parser.Write(jsonString)
+			_, err := parser.WriteString(jsonString)
 			require.NoError(t, err)
 
 			result, err := parser.GetObjects()
@@ -130,7 +142,7 @@ func TestIncompleteJsonParser_NestedObjects(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","age":30,"address":{"street":"123 Main St","city":"New York"`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -167,7 +179,7 @@ func TestIncompleteJsonParser_Reset(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","age":30,"city":"New `
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -191,7 +203,7 @@ func TestIncompleteJsonParser_TrailingWhitespaces(t *testing.T) {
 	jsonString := `{"name":"John","age":30,"city":"New York"}
   `
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -210,7 +222,7 @@ func TestIncompleteJsonParser_RedundantComma(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","age":30,"city":"New York",`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -229,7 +241,7 @@ func TestIncompleteJsonParser_SameValueRepeated(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","age":30,"city":"New York`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result1, err := parser.GetObjects()
@@ -252,7 +264,7 @@ func TestIncompleteJsonParser_EndWithColon(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","age":30,"city":`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -271,7 +283,7 @@ func TestIncompleteJsonParser_IncrementalKey(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","age":30,"cit`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -286,7 +298,7 @@ func TestIncompleteJsonParser_IncrementalKey(t *testing.T) {
 	require.Equal(t, expected, result)
 
 	// Continue writing
-	err = parser.Write("y")
+	_, err = parser.WriteString("y")
 	require.NoError(t, err)
 
 	result, err = parser.GetObjects()
@@ -301,7 +313,7 @@ func TestIncompleteJsonParser_IncrementalKey(t *testing.T) {
 	require.Equal(t, expected, result)
 
 	// Add quote and colon
-	err = parser.Write(`":`)
+	_, err = parser.WriteString(`":`)
 	require.NoError(t, err)
 
 	result, err = parser.GetObjects()
@@ -330,7 +342,7 @@ func TestIncompleteJsonParser_ComplexNestedObject(t *testing.T) {
     }
   }`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -362,7 +374,7 @@ func TestIncompleteJsonParser_ComplexNestedWithArray(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","age":30,"address":{"street":"123 Main St","city":"New York","zip":10001, "alias": ["Dante"`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -395,7 +407,7 @@ func TestIncompleteJsonParser_EscapeCharacters(t *testing.T) {
 	jsonBytes, _ := json.Marshal(obj)
 	jsonString := string(jsonBytes)
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -422,7 +434,7 @@ func TestIncompleteJsonParser_UnicodeCharacters(t *testing.T) {
 	jsonBytes, _ := json.Marshal(obj)
 	jsonString := string(jsonBytes)
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -439,7 +451,7 @@ func TestIncompleteJsonParser_EscapedCharactersInStrings(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","message":"Hello, \"World\"! [{}]"}`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -457,7 +469,7 @@ func TestIncompleteJsonParser_IgnoreExtraCharacters(t *testing.T) {
 	parser := NewIncompleteJsonParser(WithIgnoreExtraCharacters(true))
 	input := `{"message": "こんにちは世界"}\n\nこれは追加のテキストです。`
 
-	err := parser.Write(input)
+	_, err := parser.WriteString(input)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -476,7 +488,7 @@ func TestIncompleteJsonParser_UnescapedNewlines(t *testing.T) {
 	input := `{"response_text": "Hello
 World"}`
 
-	err := parser.Write(input)
+	_, err := parser.WriteString(input)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -496,7 +508,7 @@ func TestIncompleteJsonParser_UnescapedNewlines_Disabled(t *testing.T) {
 	input := `{"response_text": "Hello
 World"}`
 
-	err := parser.Write(input)
+	_, err := parser.WriteString(input)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -560,7 +572,7 @@ func TestIncompleteJsonParser_EmptyFields(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	input := `{"response_text": "", "name": "test"}`
 
-	err := parser.Write(input)
+	_, err := parser.WriteString(input)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -579,7 +591,7 @@ func TestIncompleteJsonParser_EmptyString(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	input := `{"message": ""}`
 
-	err := parser.Write(input)
+	_, err := parser.WriteString(input)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -597,7 +609,7 @@ func TestIncompleteJsonParser_EmptyArray(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	input := `{"items": []}`
 
-	err := parser.Write(input)
+	_, err := parser.WriteString(input)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -615,7 +627,7 @@ func TestIncompleteJsonParser_EmptyObject(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	input := `{"data": {}}`
 
-	err := parser.Write(input)
+	_, err := parser.WriteString(input)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -633,7 +645,7 @@ func TestIncompleteJsonParser_ComprehensiveEmptyFields(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	input := `{"str": "", "arr": [], "obj": {}}`
 
-	err := parser.Write(input)
+	_, err := parser.WriteString(input)
 	require.NoError(t, err)
 
 	result, err := parser.GetObjects()
@@ -784,6 +796,21 @@ func TestWithRequiredFields(t *testing.T) {
 		require.Equal(t, 1, user.ID)
 		require.Equal(t, "", user.Name) // ゼロ値
 	})
+
+	t.Run("EmbeddedStructFieldsNotRequired", func(t *testing.T) {
+		type Extra struct {
+			E string `json:"e"`
+		}
+		type Wrapper struct {
+			Extra
+			ID int `json:"id"`
+		}
+		var wrapper Wrapper
+		// 埋め込み構造体のフィールドは検証対象外
+		err := UnmarshalTo(`{"id": 1}`, &wrapper, WithRequiredFields(true))
+		require.NoError(t, err)
+		require.Equal(t, 1, wrapper.ID)
+	})
 }
 
 func TestParseAs_WithRequiredFields(t *testing.T) {
@@ -837,7 +864,7 @@ func TestIncompleteJsonParser_UnmarshalTo(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","age":30,"city":"New York"}`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	var person Person
@@ -853,7 +880,7 @@ func TestIncompleteJsonParser_UnmarshalTo_IncompleteJSON(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","age":30,"city":"New York"`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	var person Person
@@ -869,7 +896,7 @@ func TestIncompleteJsonParser_UnmarshalTo_NestedStruct(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"John","age":30,"address":{"street":"123 Main St","city":"New York","zipCode":"10001"`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	var person PersonWithAddress
@@ -899,7 +926,7 @@ func TestGetObjectsAs_Generics(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"name":"Bob","age":35,"city":"London"}`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	person, err := GetObjectsAs[Person](parser)
@@ -925,7 +952,7 @@ func TestIncompleteJsonParser_UnmarshalTo_BlogPost(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"title": "Go言語の素晴らしさ", "content": "Goは非常にシンプルで効率的なプログラミング言語です。", "tags": ["プログラミング", "Go"], "comments": ["勉強になりました"]}`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	var blog BlogPost
@@ -942,7 +969,7 @@ func TestIncompleteJsonParser_UnmarshalTo_IncompleteBlogPost(t *testing.T) {
 	parser := NewIncompleteJsonParser()
 	jsonString := `{"title": "日本の四季について", "content": "日本には美しい四季があります。春は桜、夏は祭り、秋は紅葉、冬は雪景色。それぞれの季節に独特の魅力があり、多くの人々を魅了しています。\n\n"`
 
-	err := parser.Write(jsonString)
+	_, err := parser.WriteString(jsonString)
 	require.NoError(t, err)
 
 	var blog BlogPost
@@ -954,3 +981,2673 @@ func TestIncompleteJsonParser_UnmarshalTo_IncompleteBlogPost(t *testing.T) {
 	require.Empty(t, blog.Tags)
 	require.Empty(t, blog.Comments)
 }
+
+func TestIncompleteJsonParser_SplitUTF8Sequence(t *testing.T) {
+	input := []byte(`{"message": "こんにちは世界"}`)
+
+	// Split at every possible byte offset, including inside multi-byte characters
+	for i := 1; i < len(input); i++ {
+		parser := NewIncompleteJsonParser()
+
+		n, err := parser.Write(input[:i])
+		require.NoError(t, err)
+		require.Equal(t, i, n)
+
+		n, err = parser.Write(input[i:])
+		require.NoError(t, err)
+		require.Equal(t, len(input)-i, n)
+
+		result, err := parser.GetObjects()
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"message": "こんにちは世界"}, result)
+	}
+}
+
+func TestIncompleteJsonParser_SplitUTF8SequencePartialResult(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+	input := []byte(`{"message": "日本`)
+
+	// Cut the last character in the middle
+	_, err := parser.Write(input[:len(input)-1])
+	require.NoError(t, err)
+
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"message": "日"}, result)
+
+	_, err = parser.Write(input[len(input)-1:])
+	require.NoError(t, err)
+
+	result, err = parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"message": "日本"}, result)
+}
+
+func TestIncompleteJsonParser_IoCopy(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+	reader := iotest.OneByteReader(strings.NewReader(`{"name":"太郎","tags":["a","b"]}`))
+
+	_, err := io.Copy(parser, reader)
+	require.NoError(t, err)
+
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+
+	expected := map[string]interface{}{
+		"name": "太郎",
+		"tags": []interface{}{"a", "b"},
+	}
+	require.Equal(t, expected, result)
+}
+
+func TestIncompleteJsonParser_WriteErrorOffset(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+
+	n, err := parser.Write([]byte(`{"a":1}x`))
+	require.Error(t, err)
+	require.Equal(t, 7, n)
+}
+
+func TestParseBytes(t *testing.T) {
+	result, err := ParseBytes([]byte(`{"name":"John","age":30`))
+	require.NoError(t, err)
+
+	expected := map[string]interface{}{
+		"name": "John",
+		"age":  float64(30),
+	}
+	require.Equal(t, expected, result)
+}
+
+func TestParseReader(t *testing.T) {
+	reader := iotest.HalfReader(strings.NewReader(`["りんご","バナナ"`))
+
+	result, err := ParseReader(reader)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"りんご", "バナナ"}, result)
+}
+
+func TestParseReader_ReadError(t *testing.T) {
+	reader := iotest.TimeoutReader(strings.NewReader(`{"a":1`))
+
+	_, err := ParseReader(iotest.OneByteReader(reader))
+	require.ErrorIs(t, err, iotest.ErrTimeout)
+}
+
+func TestIncompleteJsonParser_EscapesSplitAcrossChunks(t *testing.T) {
+	input := `{"text":"a\"b\\c\/d\né😀e"}`
+	expected := map[string]interface{}{"text": "a\"b\\c/d\né😀e"}
+
+	for i := 1; i < len(input); i++ {
+		parser := NewIncompleteJsonParser()
+
+		_, err := parser.WriteString(input[:i])
+		require.NoError(t, err)
+
+		// Partial snapshots never contain half-decoded escapes
+		result, err := parser.GetObjects()
+		require.NoError(t, err)
+		if text, ok := result.(map[string]interface{})["text"].(string); ok {
+			require.True(t, strings.HasPrefix("a\"b\\c/d\né😀e", text), "chunk %q gave %q", input[:i], text)
+		}
+
+		_, err = parser.WriteString(input[i:])
+		require.NoError(t, err)
+
+		result, err = parser.GetObjects()
+		require.NoError(t, err)
+		require.Equal(t, expected, result)
+	}
+}
+
+func TestIncompleteJsonParser_UnpairedSurrogate(t *testing.T) {
+	result, err := Parse(`["\ud83dx", "\ude00"]`)
+	require.NoError(t, err)
+
+	var expected interface{}
+	require.NoError(t, json.Unmarshal([]byte(`["\ud83dx", "\ude00"]`), &expected))
+	require.Equal(t, expected, result)
+}
+
+func TestIncompleteJsonParser_InvalidEscape(t *testing.T) {
+	_, err := Parse(`{"text":"\x41"}`)
+	require.Error(t, err)
+}
+
+func TestIncompleteJsonParser_LongStreamedString(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+	text := strings.Repeat("あいうえお\\n", 20000)
+
+	_, err := parser.WriteString(`{"text":"`)
+	require.NoError(t, err)
+	for i := 0; i < len(text); i += 7 {
+		_, err = parser.WriteString(text[i:min(i+7, len(text))])
+		require.NoError(t, err)
+	}
+	_, err = parser.WriteString(`"}`)
+	require.NoError(t, err)
+
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"text": strings.Repeat("あいうえお\n", 20000)}, result)
+}
+
+func BenchmarkIncompleteJsonParser_LongString(b *testing.B) {
+	chunk := []byte(strings.Repeat("a", 64))
+	for i := 0; i < b.N; i++ {
+		parser := NewIncompleteJsonParser()
+		_, _ = parser.WriteString(`{"text":"`)
+		for j := 0; j < 3200; j++ {
+			_, _ = parser.Write(chunk)
+		}
+	}
+}
+
+func TestIncompleteJsonParser_SnapshotReusesCompletedValues(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+
+	_, err := parser.WriteString(`{"done":{"a":[1,2]},"items":[{"x":1},`)
+	require.NoError(t, err)
+
+	first, err := parser.GetObjects()
+	require.NoError(t, err)
+	done := first.(map[string]interface{})["done"]
+	item := first.(map[string]interface{})["items"].([]interface{})[0]
+
+	_, err = parser.WriteString(`{"x":2}`)
+	require.NoError(t, err)
+
+	second, err := parser.GetObjects()
+	require.NoError(t, err)
+
+	// Completed subtrees are materialized once and shared between snapshots
+	require.Equal(t, reflect.ValueOf(done).Pointer(), reflect.ValueOf(second.(map[string]interface{})["done"]).Pointer())
+	require.Equal(t, reflect.ValueOf(item).Pointer(), reflect.ValueOf(second.(map[string]interface{})["items"].([]interface{})[0]).Pointer())
+
+	expected := map[string]interface{}{
+		"done":  map[string]interface{}{"a": []interface{}{float64(1), float64(2)}},
+		"items": []interface{}{map[string]interface{}{"x": float64(1)}, map[string]interface{}{"x": float64(2)}},
+	}
+	require.Equal(t, expected, second)
+}
+
+func TestIncompleteJsonParser_SnapshotAfterEveryRune(t *testing.T) {
+	input := `{"a":{"cit":1,"city":"Tokyo","list":[1,[2,3],{"k":true}]},"a":null,"b":"xé"}`
+
+	parser := NewIncompleteJsonParser()
+	for i, letter := range input {
+		_, err := parser.WriteString(string(letter))
+		require.NoError(t, err)
+
+		// Every cached snapshot must match a fresh parse of the same prefix
+		result, err := parser.GetObjects()
+		require.NoError(t, err)
+		fresh, err := Parse(input[:i+utf8.RuneLen(letter)])
+		require.NoError(t, err)
+		require.Equal(t, fresh, result, "prefix %q", input[:i+1])
+	}
+}
+
+func TestIncompleteJsonParser_SnapshotsNeverChange(t *testing.T) {
+	input := `{"a":"xy","b":[1,[2,"z"],{"k":tr`
+	input += `ue}],"a":{"n":1},"c":3}`
+
+	options := map[string][]ParserOption{
+		"default":   nil,
+		"ordered":   {WithOrderedObjects(true)},
+		"collect":   {WithDuplicateKeys(DuplicateKeysCollect)},
+		"completed": {WithSnapshotPolicy(SnapshotCompleted)},
+	}
+	for name, opts := range options {
+		t.Run(name, func(t *testing.T) {
+			parser := NewIncompleteJsonParser(append(opts, WithImmutableSnapshots(true))...)
+			var kept []interface{}
+			var copies []interface{}
+			for _, letter := range input {
+				_, err := parser.WriteString(string(letter))
+				require.NoError(t, err)
+
+				result, err := parser.GetObjects()
+				require.NoError(t, err)
+				kept = append(kept, result)
+				copies = append(copies, copySnapshot(result))
+
+				// Every snapshot taken before is unchanged by the writes since
+				for i := range kept {
+					require.Equal(t, copies[i], kept[i], "snapshot %d", i)
+				}
+			}
+		})
+	}
+
+	// Appending to a returned slice does not reach the next snapshot
+	parser := NewIncompleteJsonParser()
+	_, err := parser.WriteString(`[1,2`)
+	require.NoError(t, err)
+	first, err := parser.GetObjects()
+	require.NoError(t, err)
+	_ = append(first.([]interface{}), "appended")
+	_, err = parser.WriteString(`,3`)
+	require.NoError(t, err)
+	second, err := parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{float64(1), float64(2)}, first)
+	require.Equal(t, []interface{}{float64(1), float64(2), float64(3)}, second)
+}
+
+func TestIncompleteJsonParser_SnapshotsUpdatedInPlace(t *testing.T) {
+	input := `{"a":{"cit":1,"city":"Tokyo","list":[1,[2,3],{"k":true}]},"a":null,"b":"xé"}`
+
+	parser := NewIncompleteJsonParser()
+	var first interface{}
+	for i, letter := range input {
+		_, err := parser.WriteString(string(letter))
+		require.NoError(t, err)
+
+		result, err := parser.GetObjects()
+		require.NoError(t, err)
+		fresh, err := Parse(input[:i+utf8.RuneLen(letter)])
+		require.NoError(t, err)
+		require.Equal(t, fresh, result, "prefix %q", input[:i+1])
+		if first == nil {
+			first = result
+		}
+	}
+
+	// The first snapshot has been updated in place
+	require.Equal(t, reflect.ValueOf(first).Pointer(), reflect.ValueOf(parser.scope.GetOrAssume()).Pointer())
+}
+
+// itemsDocument returns the start of an object whose array holds n items
+func itemsDocument(n int) string {
+	var sb strings.Builder
+	sb.WriteString(`{"items":[`)
+	for i := 0; i < n; i++ {
+		sb.WriteString(`{"id":1,"name":"item","tags":["a","b"]},`)
+	}
+	return sb.String()
+}
+
+// snapshotPerChunk writes input in 16-byte chunks and takes a snapshot after every chunk
+func snapshotPerChunk(input string, opts ...ParserOption) {
+	parser := NewIncompleteJsonParser(opts...)
+	for j := 0; j < len(input); j += 16 {
+		_, _ = parser.WriteString(input[j:min(j+16, len(input))])
+		_, _ = parser.GetObjects()
+	}
+}
+
+func TestIncompleteJsonParser_SnapshotCostScalesWithChange(t *testing.T) {
+	// Bytes allocated per chunk must not grow with the document: a snapshot that copies the
+	// growing array allocates four times as much per chunk for four times as many items
+	perChunk := func(n int) float64 {
+		input := itemsDocument(n)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		snapshotPerChunk(input)
+		runtime.ReadMemStats(&after)
+		return float64(after.TotalAlloc-before.TotalAlloc) / float64(len(input)/16)
+	}
+
+	small, large := perChunk(1000), perChunk(4000)
+	require.Less(t, large, 2*small, "bytes per chunk: %.0f for 1000 items, %.0f for 4000 items", small, large)
+}
+
+func BenchmarkIncompleteJsonParser_SnapshotPerChunk(b *testing.B) {
+	for _, n := range []int{1000, 4000} {
+		input := itemsDocument(n)
+		b.Run(fmt.Sprintf("items=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				snapshotPerChunk(input)
+			}
+		})
+	}
+}
+
+func TestIncompleteJsonParser_NumberExponents(t *testing.T) {
+	testCases := map[string]float64{
+		`1e10`:     1e10,
+		`2.5E-3`:   2.5e-3,
+		`6.02e+23`: 6.02e+23,
+		`-0.5e2`:   -50,
+		`0`:        0,
+		`-0`:       0,
+		`0e5`:      0,
+	}
+
+	for input, expected := range testCases {
+		t.Run(input, func(t *testing.T) {
+			result, err := Parse(`{"n":` + input + `}`)
+			require.NoError(t, err)
+			require.Equal(t, map[string]interface{}{"n": expected}, result)
+
+			result, err = Parse(`[` + input + `, ` + input + `]`)
+			require.NoError(t, err)
+			require.Equal(t, []interface{}{expected, expected}, result)
+		})
+	}
+}
+
+func TestIncompleteJsonParser_PartialNumbers(t *testing.T) {
+	testCases := map[string]float64{
+		`-`:      0,
+		`12`:     12,
+		`0.`:     0,
+		`12.`:    12,
+		`12.5`:   12.5,
+		`1e`:     1,
+		`1e-`:    1,
+		`1e+`:    1,
+		`1.5E`:   1.5,
+		`-2.5e1`: -25,
+	}
+
+	for input, expected := range testCases {
+		t.Run(input, func(t *testing.T) {
+			result, err := Parse(input)
+			require.NoError(t, err)
+			require.Equal(t, expected, result)
+			require.IsType(t, float64(0), result)
+
+			result, err = Parse(`{"n":` + input)
+			require.NoError(t, err)
+			require.Equal(t, map[string]interface{}{"n": expected}, result)
+		})
+	}
+}
+
+func TestIncompleteJsonParser_TopLevelNumberTermination(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+
+	_, err := parser.WriteString("42")
+	require.NoError(t, err)
+	_, err = parser.WriteString(" \n")
+	require.NoError(t, err)
+
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, float64(42), result)
+
+	// The number is finished, so more content is extra
+	_, err = parser.WriteString("1")
+	require.Error(t, err)
+}
+
+func TestIncompleteJsonParser_InvalidNumbers(t *testing.T) {
+	testCases := []string{`[01]`, `{"n":-01}`, `[1.e5]`, `[1x]`, `[1e5.0]`, `[--1]`, `00`}
+
+	for _, input := range testCases {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(input)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestWithUseNumber(t *testing.T) {
+	parser := NewIncompleteJsonParser(WithUseNumber(true))
+	_, err := parser.WriteString(`{"id":12345678901234567891,"price":19.99,"partial":1.5e`)
+	require.NoError(t, err)
+
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+
+	expected := map[string]interface{}{
+		"id":      json.Number("12345678901234567891"),
+		"price":   json.Number("19.99"),
+		"partial": json.Number("1.5"),
+	}
+	require.Equal(t, expected, result)
+}
+
+func TestWithNumberMode(t *testing.T) {
+	input := `[9007199254740993, -3, 2.5, 1e3, 123456789012345678901234567890`
+
+	t.Run("Int64", func(t *testing.T) {
+		result, err := Parse(input, WithNumberMode(NumberInt64))
+		require.NoError(t, err)
+
+		expected := []interface{}{int64(9007199254740993), int64(-3), 2.5, float64(1000), 1.2345678901234568e+29}
+		require.Equal(t, expected, result)
+	})
+
+	t.Run("Big", func(t *testing.T) {
+		result, err := Parse(input, WithNumberMode(NumberBig))
+		require.NoError(t, err)
+
+		values := result.([]interface{})
+		require.Equal(t, "9007199254740993", values[0].(*big.Int).String())
+		require.Equal(t, "-3", values[1].(*big.Int).String())
+		require.Equal(t, "2.5", values[2].(*big.Float).Text('g', -1))
+		require.Equal(t, "1000", values[3].(*big.Float).Text('f', -1))
+		require.Equal(t, "123456789012345678901234567890", values[4].(*big.Int).String())
+	})
+}
+
+func TestIncompleteJsonParser_NumberOverflow(t *testing.T) {
+	// Numbers out of the range of float64 become ±Inf, as strconv.ParseFloat returns them
+	for _, options := range [][]ParserOption{nil, {WithStrict(true)}} {
+		result, err := Parse(`[1e400, -1e400, 1e-400]`, options...)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{math.Inf(1), math.Inf(-1), float64(0)}, result)
+	}
+
+	// Decoding reports them like encoding/json
+	for _, input := range []string{`1e400 `, `[1e400]`, `{"n": 1e400}`} {
+		var actual, expected interface{}
+		expectedErr := json.Unmarshal([]byte(input), &expected)
+		require.Error(t, expectedErr)
+		require.EqualError(t, UnmarshalTo(input, &actual), expectedErr.Error(), input)
+	}
+
+	// Until the number is complete it is left at its zero value
+	var partial []interface{}
+	require.NoError(t, UnmarshalTo(`[1, 1e400`, &partial))
+	require.Equal(t, []interface{}{float64(1), nil}, partial)
+}
+
+func TestWithNumberDecoder(t *testing.T) {
+	decoder := func(lexeme string) interface{} {
+		return "n:" + lexeme
+	}
+
+	result, err := Parse(`{"a":10,"b":-`, WithNumberDecoder(decoder))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"a": "n:10", "b": "n:0"}, result)
+}
+
+func TestUnmarshalTo_NumberModes(t *testing.T) {
+	type Order struct {
+		ID     int64       `json:"id"`
+		Amount float64     `json:"amount"`
+		Big    *big.Int    `json:"big"`
+		Extra  interface{} `json:"extra"`
+	}
+	input := `{"id":9007199254740993,"amount":1234567890.123456789,"big":123456789012345678901234567890,"extra":7`
+
+	t.Run("UseNumber", func(t *testing.T) {
+		order, err := ParseAs[Order](input, WithUseNumber(true))
+		require.NoError(t, err)
+		require.Equal(t, int64(9007199254740993), order.ID)
+		require.Equal(t, "123456789012345678901234567890", order.Big.String())
+		require.Equal(t, json.Number("7"), order.Extra)
+	})
+
+	t.Run("Big", func(t *testing.T) {
+		var order Order
+		err := UnmarshalTo(input, &order, WithNumberMode(NumberBig))
+		require.NoError(t, err)
+		require.Equal(t, int64(9007199254740993), order.ID)
+		require.Equal(t, 1234567890.123456789, order.Amount)
+		require.Equal(t, "123456789012345678901234567890", order.Big.String())
+	})
+
+	t.Run("Decoder", func(t *testing.T) {
+		decoder := func(lexeme string) interface{} { return "n" + lexeme }
+		order, err := ParseAs[Order](input, WithNumberDecoder(decoder))
+		require.NoError(t, err)
+		require.Equal(t, int64(9007199254740993), order.ID)
+		require.Equal(t, "n7", order.Extra)
+
+		var target struct{ N interface{} }
+		require.NoError(t, UnmarshalTo(`{"N": [1, {"m": 2}]}`, &target, WithNumberDecoder(decoder)))
+		require.Equal(t, []interface{}{"n1", map[string]interface{}{"m": "n2"}}, target.N)
+	})
+}
+
+func TestUnmarshalTo_TextUnmarshalerMismatch(t *testing.T) {
+	var float struct {
+		F *big.Float `json:"f"`
+	}
+	err := UnmarshalTo(`{"f": 1.5}`, &float)
+	var typeErr *json.UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+	require.Equal(t, "number", typeErr.Value)
+	require.Equal(t, reflect.TypeOf(big.Float{}), typeErr.Type)
+
+	var ip struct {
+		IP net.IP `json:"ip"`
+	}
+	for _, input := range []string{`{"ip": true}`, `{"ip": 1}`} {
+		err = UnmarshalTo(input, &ip)
+		require.ErrorAs(t, err, &typeErr, input)
+		require.Equal(t, reflect.TypeOf(net.IP{}), typeErr.Type)
+	}
+
+	require.NoError(t, UnmarshalTo(`{"ip": "10.0.0.1"}`, &ip))
+	require.Equal(t, "10.0.0.1", ip.IP.String())
+}
+
+func TestWithOrderedObjects(t *testing.T) {
+	parser := NewIncompleteJsonParser(WithOrderedObjects(true))
+
+	_, err := parser.WriteString(`{"zeta":1,"alpha":{"y":true,"x":null},"mid`)
+	require.NoError(t, err)
+
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+
+	object, ok := result.(*OrderedObject)
+	require.True(t, ok)
+	require.Equal(t, []string{"zeta", "alpha", "mid"}, object.Keys())
+
+	alpha, ok := object.Get("alpha")
+	require.True(t, ok)
+	require.Equal(t, []string{"y", "x"}, alpha.(*OrderedObject).Keys())
+
+	// The partial key grows in place and keeps its position
+	_, err = parser.WriteString(`dle":"v","beta":[{"b":1,"a":2}]}`)
+	require.NoError(t, err)
+
+	result, err = parser.GetObjects()
+	require.NoError(t, err)
+	object = result.(*OrderedObject)
+	require.Equal(t, []string{"zeta", "alpha", "middle", "beta"}, object.Keys())
+
+	encoded, err := json.Marshal(object)
+	require.NoError(t, err)
+	require.Equal(t, `{"zeta":1,"alpha":{"y":true,"x":null},"middle":"v","beta":[{"b":1,"a":2}]}`, string(encoded))
+}
+
+func TestWithOrderedObjects_DuplicateKeepsFirstPosition(t *testing.T) {
+	result, err := Parse(`{"a":1,"b":2,"a":3}`, WithOrderedObjects(true))
+	require.NoError(t, err)
+
+	object := result.(*OrderedObject)
+	require.Equal(t, []string{"a", "b"}, object.Keys())
+	value, _ := object.Get("a")
+	require.Equal(t, float64(3), value)
+}
+
+func TestWithOrderedObjects_UnmarshalTo(t *testing.T) {
+	person, err := ParseAs[Person](`{"city":"Tokyo","name":"Hanako","age":28`, WithOrderedObjects(true), WithRequiredFields(true))
+	require.NoError(t, err)
+	require.Equal(t, Person{Name: "Hanako", Age: 28, City: "Tokyo"}, person)
+
+	_, err = ParseAs[Person](`{"name":"Hanako"}`, WithOrderedObjects(true), WithRequiredFields(true))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing required fields: age, city")
+}
+
+func TestWithDuplicateKeys(t *testing.T) {
+	input := `{"a":1,"b":{"c":true},"a":2,"a":"three"}`
+
+	t.Run("LastWins", func(t *testing.T) {
+		result, err := Parse(input, WithDuplicateKeys(DuplicateKeysLastWins))
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"a": "three", "b": map[string]interface{}{"c": true}}, result)
+	})
+
+	t.Run("FirstWins", func(t *testing.T) {
+		result, err := Parse(input, WithDuplicateKeys(DuplicateKeysFirstWins))
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"a": float64(1), "b": map[string]interface{}{"c": true}}, result)
+	})
+
+	t.Run("Collect", func(t *testing.T) {
+		result, err := Parse(input, WithDuplicateKeys(DuplicateKeysCollect))
+		require.NoError(t, err)
+		expected := map[string]interface{}{
+			"a": []interface{}{float64(1), float64(2), "three"},
+			"b": map[string]interface{}{"c": true},
+		}
+		require.Equal(t, expected, result)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		_, err := Parse(`{"list":[{"id":1,"id":2}]}`, WithDuplicateKeys(DuplicateKeysError))
+		require.Error(t, err)
+
+		var duplicateErr *DuplicateKeyError
+		require.ErrorAs(t, err, &duplicateErr)
+		require.Equal(t, "id", duplicateErr.Key)
+		require.Equal(t, "/list/0/id", duplicateErr.Path.String())
+		require.Contains(t, err.Error(), `duplicate key "id" at /list/0/id`)
+	})
+}
+
+func TestWithDuplicateKeys_PartialSnapshots(t *testing.T) {
+	t.Run("FirstWins", func(t *testing.T) {
+		parser := NewIncompleteJsonParser(WithDuplicateKeys(DuplicateKeysFirstWins))
+		_, err := parser.WriteString(`{"a":"first","a":"sec`)
+		require.NoError(t, err)
+
+		result, err := parser.GetObjects()
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"a": "first"}, result)
+	})
+
+	t.Run("Collect", func(t *testing.T) {
+		parser := NewIncompleteJsonParser(WithDuplicateKeys(DuplicateKeysCollect))
+		_, err := parser.WriteString(`{"a":1,"a":2,"a":[3`)
+		require.NoError(t, err)
+
+		result, err := parser.GetObjects()
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"a": []interface{}{float64(1), float64(2), []interface{}{float64(3)}}}, result)
+
+		_, err = parser.WriteString(`,4]}`)
+		require.NoError(t, err)
+
+		result, err = parser.GetObjects()
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"a": []interface{}{float64(1), float64(2), []interface{}{float64(3), float64(4)}}}, result)
+	})
+}
+
+func TestPath_String(t *testing.T) {
+	require.Equal(t, "", Path{}.String())
+	require.Equal(t, "/items/0/a~1b/c~0d", Path{"items", 0, "a/b", "c~d"}.String())
+}
+
+func TestParseError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		line     int
+		column   int
+		offset   int64
+		char     rune
+		path     string
+		expected string
+	}{
+		{"MissingColon", `{"a" 1}`, 1, 6, 5, '1', "", "':'"},
+		{"BadSeparator", `{"a":1;}`, 1, 7, 6, ';', "", "',' or '}'"},
+		{"BadKey", `{"a":{1}}`, 1, 7, 6, '1', "/a", "object key or '}'"},
+		{"BadEscape", "{\"a\":\"ok\",\n  \"b\": \"c\\q\"}", 2, 11, 21, 'q', "/b", "escape character"},
+		{"BadKeyword", `{"x":{"y":tx}}`, 1, 12, 11, 'x', "/x/y", "true"},
+		{"BadValue", `["é", @]`, 1, 7, 7, '@', "/1", "value"},
+		{"BadArraySeparator", `[1 2]`, 1, 4, 3, '2', "", "',' or ']'"},
+		{"AfterNumber", `42x`, 1, 3, 2, 'x', "", "whitespace or end of input"},
+		{"LeadingZero", `007`, 1, 2, 1, '0', "", "whitespace or end of input"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.input)
+			require.Error(t, err)
+
+			var parseErr *ParseError
+			require.ErrorAs(t, err, &parseErr)
+			require.Equal(t, tc.line, parseErr.Line)
+			require.Equal(t, tc.column, parseErr.Column)
+			require.Equal(t, tc.offset, parseErr.Offset)
+			require.Equal(t, tc.char, parseErr.Char)
+			require.Equal(t, tc.path, parseErr.Path.String())
+			require.Equal(t, tc.expected, parseErr.Expected)
+			require.Contains(t, err.Error(), "failed to parse the JSON string")
+			require.NotErrorIs(t, err, ErrAlreadyFinished)
+		})
+	}
+
+	_, err := Parse(`007`, WithStrict(true))
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, '0', parseErr.Char)
+	require.NotErrorIs(t, err, ErrAlreadyFinished)
+}
+
+func TestParseError_AcrossChunks(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+
+	_, err := parser.WriteString("{\"名前\":\n")
+	require.NoError(t, err)
+	_, err = parser.WriteString(`  "太郎",`)
+	require.NoError(t, err)
+	_, err = parser.WriteString(` 1}`)
+	require.Error(t, err)
+
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, 2, parseErr.Line)
+	require.Equal(t, 9, parseErr.Column)
+	require.Equal(t, int64(23), parseErr.Offset)
+	require.Equal(t, int64(15), parseErr.RuneOffset)
+	require.Equal(t, "object key or '}'", parseErr.Expected)
+	require.Equal(t, `failed to parse the JSON string at line 2, column 9 (offset 23): unexpected '1', expected object key or '}'`, err.Error())
+}
+
+func TestParseError_Sentinels(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+
+	_, err := parser.GetObjects()
+	require.ErrorIs(t, err, ErrNoInput)
+
+	_, err = parser.WriteString(`{"a":1} x`)
+	require.ErrorIs(t, err, ErrAlreadyFinished)
+
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, int64(8), parseErr.Offset)
+
+	var result map[string]interface{}
+	err = UnmarshalTo(`null`, &result)
+	require.ErrorIs(t, err, ErrNullValue)
+}
+
+func TestParseError_DuplicateKeyIsFatal(t *testing.T) {
+	parser := NewIncompleteJsonParser(WithDuplicateKeys(DuplicateKeysError))
+
+	_, err := parser.WriteString(`{"a":1,"a"`)
+	require.Error(t, err)
+
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, int64(9), parseErr.Offset)
+
+	// The parser stays failed until Reset
+	_, err = parser.WriteString(`:2}`)
+	require.ErrorIs(t, err, parseErr)
+
+	parser.Reset()
+	_, err = parser.WriteString(`{"a":1}`)
+	require.NoError(t, err)
+}
+
+func TestWithStrict_AgreesWithEncodingJSON(t *testing.T) {
+	corpus := []string{
+		`{"a":1,"b":[true,false,null],"c":{"d":"e"}}`,
+		`[1, 2.5, -0, 1e10, 2.5E-3, 6.02e+23]`,
+		` "text" `,
+		"\t[\r\n]\n",
+		`"é😀\n"`,
+		`42`,
+		`[]`,
+		`{}`,
+		`007`,
+		`[007]`,
+		`[1,]`,
+		`{"a":1,}`,
+		`[tr,`,
+		`[tr]`,
+		`{"a":nul}`,
+		`[1 2]`,
+		`{"a" 1}`,
+		`{"a":1 "b":2}`,
+		`[-]`,
+		`[1.]`,
+		`[1e]`,
+		`[1e+]`,
+		`-`,
+		`1.`,
+		`.5`,
+		`+1`,
+		"\u00a0[]",
+		"[\u00a01]",
+		"[1,\u2028 2]",
+		"\"a\x01b\"",
+		"\"a\tb\"",
+		"\"a\nb\"",
+		`{"a":1} x`,
+		`[1]]`,
+		`{"a":[1,{"b":2}]}`,
+		`{"a"`,
+		`[`,
+		``,
+		`   `,
+		`"unterminated`,
+		`"bad \x escape"`,
+		`{'a':1}`,
+		`[1,,2]`,
+		`[,1]`,
+	}
+
+	for _, input := range corpus {
+		t.Run(input, func(t *testing.T) {
+			parser := NewIncompleteJsonParser(WithStrict(true))
+			_, err := parser.WriteString(input)
+			if err == nil {
+				err = parser.Close()
+			}
+			require.Equal(t, json.Valid([]byte(input)), err == nil, "error: %v", err)
+		})
+	}
+}
+
+func TestWithStrict_PositionedErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		offset   int64
+		expected string
+	}{
+		{"trailing comma in array", `[1,]`, 3, "value"},
+		{"trailing comma in object", `{"a":1,}`, 7, "object key"},
+		{"control character", "[\"a\x01\"]", 3, "escaped control character"},
+		{"incomplete number", `[1.]`, 3, "digit"},
+		{"leading zero", `[007]`, 2, "',' or ']'"},
+		{"non-breaking space", "[\u00a01]", 1, "value"},
+		{"line separator", "{\"a\":\u20281}", 5, "value"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := NewIncompleteJsonParser(WithStrict(true))
+			_, err := parser.WriteString(tc.input)
+			require.Error(t, err)
+
+			var parseErr *ParseError
+			require.ErrorAs(t, err, &parseErr)
+			require.Equal(t, tc.offset, parseErr.Offset)
+			require.Equal(t, tc.expected, parseErr.Expected)
+		})
+	}
+}
+
+func TestWithStrict_PartialInput(t *testing.T) {
+	parser := NewIncompleteJsonParser(WithStrict(true))
+
+	_, err := parser.WriteString(`{"a":[1,2`)
+	require.NoError(t, err)
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"a": []interface{}{float64(1), float64(2)}}, result)
+
+	err = parser.Close()
+	require.ErrorIs(t, err, ErrUnexpectedEnd)
+}
+
+func TestIncompleteJsonParser_Close(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+	_, err := parser.WriteString(`12`)
+	require.NoError(t, err)
+	require.NoError(t, parser.Close())
+
+	parser = NewIncompleteJsonParser()
+	require.ErrorIs(t, parser.Close(), ErrUnexpectedEnd)
+
+	parser = NewIncompleteJsonParser()
+	_, err = parser.Write([]byte("\"\xe3\x81"))
+	require.NoError(t, err)
+	require.ErrorIs(t, parser.Close(), ErrUnexpectedEnd)
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, "�", result)
+}
+
+func TestWithComments(t *testing.T) {
+	input := "{/* leading */\"a\": 1, // line comment\n\"url\": \"http://example.com/*not a comment*/\", \"b\": [1, /* x */ 2]/**/}"
+	expected := map[string]interface{}{
+		"a":   float64(1),
+		"url": "http://example.com/*not a comment*/",
+		"b":   []interface{}{float64(1), float64(2)},
+	}
+
+	result, err := Parse(input, WithComments(true))
+	require.NoError(t, err)
+	require.Equal(t, expected, result)
+
+	result, err = Parse(input, WithComments(true), WithStrict(true))
+	require.NoError(t, err)
+	require.Equal(t, expected, result)
+
+	_, err = Parse(input)
+	require.Error(t, err)
+}
+
+func TestWithComments_PartialInput(t *testing.T) {
+	parser := NewIncompleteJsonParser(WithComments(true))
+
+	_, err := parser.WriteString(`{"a": 12/* half-written`)
+	require.NoError(t, err)
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"a": float64(12)}, result)
+	require.ErrorIs(t, parser.Close(), ErrUnexpectedEnd)
+
+	_, err = parser.WriteString(` comment *`)
+	require.NoError(t, err)
+	_, err = parser.WriteString(`/, "b": /`)
+	require.NoError(t, err)
+	result, err = parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"a": float64(12), "b": nil}, result)
+
+	_, err = parser.WriteString(`/ x` + "\n" + `true}`)
+	require.NoError(t, err)
+	result, err = parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"a": float64(12), "b": true}, result)
+	require.NoError(t, parser.Close())
+}
+
+func TestWithComments_LoneSlash(t *testing.T) {
+	parser := NewIncompleteJsonParser(WithComments(true))
+	_, err := parser.WriteString(`[1 /x]`)
+
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, int64(4), parseErr.Offset)
+	require.Equal(t, "'/' or '*'", parseErr.Expected)
+}
+
+func TestWithComments_Remainder(t *testing.T) {
+	for _, input := range []string{`{"a":1} /* c */ `, "[1] // note\n", "42 /* end */"} {
+		parser := NewIncompleteJsonParser(WithComments(true))
+		_, err := parser.WriteString(input)
+		require.NoError(t, err)
+		require.NoError(t, parser.Close())
+		end, ok := parser.EndOffset()
+		require.True(t, ok)
+		require.Equal(t, input[end:], parser.Remainder())
+	}
+}
+
+func TestWithSingleQuotes(t *testing.T) {
+	result, err := Parse(`{'a': 'it\'s "quoted"', "b": 'x'}`, WithSingleQuotes(true))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"a": `it's "quoted"`, "b": "x"}, result)
+
+	result, err = Parse(`{'a': 'partial "text`, WithSingleQuotes(true))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"a": `partial "text`}, result)
+
+	_, err = Parse(`{'a': 1}`)
+	require.Error(t, err)
+}
+
+func TestWithUnquotedKeys(t *testing.T) {
+	result, err := Parse(`{foo: 1, $bar_2 :'x', ünï/**/: true, "quoted": null}`, WithUnquotedKeys(true), WithSingleQuotes(true), WithComments(true))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"foo": float64(1), "$bar_2": "x", "ünï": true, "quoted": nil}, result)
+
+	result, err = Parse(`{foo: 1, ba`, WithUnquotedKeys(true))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"foo": float64(1), "ba": nil}, result)
+
+	_, err = Parse(`{1a: 1}`, WithUnquotedKeys(true))
+	require.Error(t, err)
+}
+
+func TestWithJSON5(t *testing.T) {
+	input := `// JSON5 document
+{
+  unquoted: 'and you can quote me on that',
+  singleQuotes: 'I can use "double quotes" here',
+  lineBreaks: "Look, Mom! \
+No \\n's!",
+  hexadecimal: 0xdecaf,
+  leadingDecimalPoint: .8675309, andTrailing: 8675309.,
+  positiveSign: +1,
+  escapes: '\x41\v\0\q',
+  exponent: 5.e2,
+  infinities: [Infinity, -Infinity],
+  trailingComma: 'in objects', andIn: ['arrays',],
+  "backwardsCompatible": "with JSON",
+}
+`
+	expected := map[string]interface{}{
+		"unquoted":            "and you can quote me on that",
+		"singleQuotes":        `I can use "double quotes" here`,
+		"lineBreaks":          `Look, Mom! No \n's!`,
+		"hexadecimal":         float64(0xdecaf),
+		"leadingDecimalPoint": 0.8675309,
+		"andTrailing":         float64(8675309),
+		"positiveSign":        float64(1),
+		"escapes":             "A\v\x00q",
+		"exponent":            float64(500),
+		"infinities":          []interface{}{math.Inf(1), math.Inf(-1)},
+		"trailingComma":       "in objects",
+		"andIn":               []interface{}{"arrays"},
+		"backwardsCompatible": "with JSON",
+	}
+
+	parser := NewIncompleteJsonParser(WithJSON5(true), WithStrict(true))
+	_, err := parser.WriteString(input)
+	require.NoError(t, err)
+	require.NoError(t, parser.Close())
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, expected, result)
+
+	// Every prefix parses and the final snapshot matches
+	parser = NewIncompleteJsonParser(WithJSON5(true))
+	for _, letter := range input {
+		_, err := parser.WriteString(string(letter))
+		require.NoError(t, err)
+		if _, err = parser.GetObjects(); err != ErrNoInput {
+			require.NoError(t, err)
+		}
+	}
+	result, err = parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, expected, result)
+}
+
+func TestWithJSON5_Numbers(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`0x`, float64(0)},
+		{`0x1F`, float64(31)},
+		{`-0XfF`, float64(-255)},
+		{`+`, float64(0)},
+		{`.`, float64(0)},
+		{`.5`, 0.5},
+		{`-.5e1`, float64(-5)},
+		{`5.`, float64(5)},
+		{`-Inf`, math.Inf(-1)},
+		{`Infinity`, math.Inf(1)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := Parse(tc.input, WithJSON5(true))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+
+	result, err := Parse(`[NaN, -Na]`, WithJSON5(true))
+	require.NoError(t, err)
+	require.True(t, math.IsNaN(result.([]interface{})[0].(float64)))
+	require.True(t, math.IsNaN(result.([]interface{})[1].(float64)))
+
+	result, err = Parse(`0x1FFFFFFFFFFFFFFFF`, WithJSON5(true), WithNumberMode(NumberBig))
+	require.NoError(t, err)
+	expected, _ := new(big.Int).SetString("1FFFFFFFFFFFFFFFF", 16)
+	require.Equal(t, expected, result)
+
+	for _, input := range []string{`0x`, `[.]`, `[+]`, `01`, `0x1g`} {
+		parser := NewIncompleteJsonParser(WithJSON5(true), WithStrict(true))
+		_, err := parser.WriteString(input)
+		if err == nil {
+			err = parser.Close()
+		}
+		require.Error(t, err, input)
+	}
+}
+
+func TestWithPythonLiterals(t *testing.T) {
+	result, err := Parse(`{'ok': True, 'value': None, 'flag': False, "json": true, 'items': [1, 2,],}`, WithPythonLiterals(true))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"ok":    true,
+		"value": nil,
+		"flag":  false,
+		"json":  true,
+		"items": []interface{}{float64(1), float64(2)},
+	}, result)
+
+	_, err = Parse(`{'ok': True}`)
+	require.Error(t, err)
+}
+
+func TestWithPythonLiterals_PartialKeywords(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{'ok': Tr`, map[string]interface{}{"ok": true}},
+		{`{'ok': F`, map[string]interface{}{"ok": false}},
+		{`{'ok': No`, map[string]interface{}{"ok": nil}},
+		{`['a', 'b`, []interface{}{"a", "b"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := Parse(tc.input, WithPythonLiterals(true))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestWithPythonLiterals_Escapes(t *testing.T) {
+	input := `['a\'b', "\x41\101\7xé\U0001F600", 'keep \q \/', 'line\
+continued', '\a\v\0']`
+	expected := []interface{}{"a'b", "AA\axé\U0001F600", `keep \q \/`, "linecontinued", "\a\v\x00"}
+
+	result, err := Parse(input, WithPythonLiterals(true))
+	require.NoError(t, err)
+	require.Equal(t, expected, result)
+
+	// Escapes split across chunks decode the same way
+	parser := NewIncompleteJsonParser(WithPythonLiterals(true))
+	for _, letter := range input {
+		_, err := parser.WriteString(string(letter))
+		require.NoError(t, err)
+	}
+	result, err = parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, expected, result)
+}
+
+func TestWithPythonLiterals_JSONUnchanged(t *testing.T) {
+	inputs := []string{
+		`{"url": "http:\/\/x"}`,
+		`["\b\f\n\r\t\"\\", "\u00e9", "\ud83d\ude00"]`,
+		`{"nested": {"a": [1, 2.5, true, null, "x\/y"]}}`,
+	}
+	for _, input := range inputs {
+		expected, err := Parse(input)
+		require.NoError(t, err)
+		result, err := Parse(input, WithPythonLiterals(true))
+		require.NoError(t, err)
+		require.Equal(t, expected, result, input)
+	}
+}
+
+func TestWithPythonLiterals_Tuples(t *testing.T) {
+	result, err := Parse(`{'point': (1, 2), 'single': ('a',), 'empty': (), 'nested': ([1], (2, 3`, WithPythonLiterals(true))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"point":  []interface{}{float64(1), float64(2)},
+		"single": []interface{}{"a"},
+		"empty":  []interface{}{},
+		"nested": []interface{}{[]interface{}{float64(1)}, []interface{}{float64(2), float64(3)}},
+	}, result)
+
+	_, err = Parse(`(1, 2]`, WithPythonLiterals(true), WithStrict(true))
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, "',' or ')'", parseErr.Expected)
+}
+
+func TestWithMultipleDocuments(t *testing.T) {
+	var documents []Document
+	parser := NewIncompleteJsonParser(WithMultipleDocuments(true), WithOnDocument(func(document Document) {
+		documents = append(documents, document)
+	}))
+
+	_, err := parser.WriteString(`{"a":1} {"b":2}[3]"x" 4`)
+	require.NoError(t, err)
+	require.Len(t, documents, 4)
+
+	// The last number is only complete once the input ends
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, float64(4), result)
+	require.NoError(t, parser.Close())
+
+	require.Equal(t, []Document{
+		{Index: 0, Offset: 0, Value: map[string]interface{}{"a": float64(1)}},
+		{Index: 1, Offset: 8, Value: map[string]interface{}{"b": float64(2)}},
+		{Index: 2, Offset: 15, Value: []interface{}{float64(3)}},
+		{Index: 3, Offset: 18, Value: "x"},
+		{Index: 4, Offset: 22, Value: float64(4)},
+	}, documents)
+}
+
+func TestWithMultipleDocuments_Recovery(t *testing.T) {
+	var documents []Document
+	parser := NewIncompleteJsonParser(WithMultipleDocuments(true), WithOnDocument(func(document Document) {
+		documents = append(documents, document)
+	}))
+
+	_, err := parser.WriteString("{\"a\":1} {\"b\" 2} [\"rest of line\"]\n{\"c\":3}")
+	require.NoError(t, err)
+
+	require.Len(t, documents, 3)
+	require.Equal(t, map[string]interface{}{"a": float64(1)}, documents[0].Value)
+	var parseErr *ParseError
+	require.ErrorAs(t, documents[1].Err, &parseErr)
+	require.Equal(t, int64(13), parseErr.Offset)
+	require.Equal(t, 2, documents[2].Index)
+	require.Equal(t, map[string]interface{}{"c": float64(3)}, documents[2].Value)
+}
+
+func TestWithNDJSON(t *testing.T) {
+	input := "{\"a\":1}\n{\"b\":\n\n{\"c\":3}\nnot json\n12\n{\"d\":[1,2]}\n"
+
+	var documents []Document
+	parser := NewIncompleteJsonParser(WithNDJSON(true), WithOnDocument(func(document Document) {
+		documents = append(documents, document)
+	}))
+	for _, letter := range input {
+		_, err := parser.WriteString(string(letter))
+		require.NoError(t, err)
+	}
+	require.NoError(t, parser.Close())
+
+	require.Len(t, documents, 6)
+	require.Equal(t, map[string]interface{}{"a": float64(1)}, documents[0].Value)
+	require.ErrorIs(t, documents[1].Err, ErrUnexpectedEnd)
+	require.Equal(t, map[string]interface{}{"b": nil}, documents[1].Value)
+	require.Equal(t, int64(8), documents[1].Offset)
+	require.Equal(t, map[string]interface{}{"c": float64(3)}, documents[2].Value)
+	require.Error(t, documents[3].Err)
+	require.Equal(t, float64(12), documents[4].Value)
+	require.NoError(t, documents[4].Err)
+	require.Equal(t, map[string]interface{}{"d": []interface{}{float64(1), float64(2)}}, documents[5].Value)
+	for i, document := range documents {
+		require.Equal(t, i, document.Index)
+	}
+
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, documents[5].Value, result)
+}
+
+func TestWithNDJSON_OneValuePerLine(t *testing.T) {
+	var documents []Document
+	parser := NewIncompleteJsonParser(WithNDJSON(true), WithOnDocument(func(document Document) {
+		documents = append(documents, document)
+	}))
+	_, err := parser.WriteString("4 5\n{\"a\":1}\n[1]{\"b\":2}\n7[8]\n")
+	require.NoError(t, err)
+	require.NoError(t, parser.Close())
+
+	require.Len(t, documents, 7)
+	require.Equal(t, Document{Index: 0, Offset: 0, Value: float64(4)}, documents[0])
+	var parseErr *ParseError
+	require.ErrorAs(t, documents[1].Err, &parseErr)
+	require.ErrorIs(t, parseErr, ErrAlreadyFinished)
+	require.Equal(t, int64(2), parseErr.Offset)
+	require.Equal(t, int64(2), documents[1].Offset)
+	require.Nil(t, documents[1].Value)
+	require.Equal(t, Document{Index: 2, Offset: 4, Value: map[string]interface{}{"a": float64(1)}}, documents[2])
+	require.Equal(t, []interface{}{float64(1)}, documents[3].Value)
+	require.ErrorIs(t, documents[4].Err, ErrAlreadyFinished)
+	require.Equal(t, float64(7), documents[5].Value)
+	require.NoError(t, documents[5].Err)
+	require.ErrorIs(t, documents[6].Err, ErrAlreadyFinished)
+}
+
+func TestWithNDJSON_ReadFrom(t *testing.T) {
+	var values []interface{}
+	parser := NewIncompleteJsonParser(WithNDJSON(true), WithOnDocument(func(document Document) {
+		values = append(values, document.Value)
+	}))
+
+	_, err := io.Copy(parser, iotest.OneByteReader(strings.NewReader("[1]\n[\"é\"]\n3")))
+	require.NoError(t, err)
+	require.NoError(t, parser.Close())
+	require.Equal(t, []interface{}{[]interface{}{float64(1)}, []interface{}{"é"}, float64(3)}, values)
+}
+
+func TestWithEmbeddedJSON(t *testing.T) {
+	input := "Sure! Here is the data [see below]:\n```json\n{\"a\": [1, 2], \"code\": \"```\"}\n```\n" +
+		"The second one is inline: {\"b\": \"x`y\"}, and some code:\n```python\nprint({1})\n```\n" +
+		"```\n[3]\n```\nLet me know…"
+
+	var documents []Document
+	parser := NewIncompleteJsonParser(WithEmbeddedJSON(true), WithOnDocument(func(document Document) {
+		documents = append(documents, document)
+	}))
+	for _, letter := range input {
+		_, err := parser.WriteString(string(letter))
+		require.NoError(t, err)
+	}
+	require.NoError(t, parser.Close())
+
+	require.Equal(t, []Document{
+		{Index: 0, Offset: int64(strings.Index(input, `{"a"`)), Value: map[string]interface{}{"a": []interface{}{float64(1), float64(2)}, "code": "```"}},
+		{Index: 1, Offset: int64(strings.Index(input, `{"b"`)), Value: map[string]interface{}{"b": "x`y"}},
+		{Index: 2, Offset: int64(strings.Index(input, `[3]`)), Value: []interface{}{float64(3)}},
+	}, documents)
+}
+
+func TestWithEmbeddedJSON_ProseInBrackets(t *testing.T) {
+	input := "Sure, see [this] and [note], [first], [tr] or [1, 2] and {\"a\": 1}"
+
+	var documents []Document
+	parser := NewIncompleteJsonParser(WithEmbeddedJSON(true), WithOnDocument(func(document Document) {
+		documents = append(documents, document)
+	}))
+	_, err := parser.WriteString(input)
+	require.NoError(t, err)
+	require.NoError(t, parser.Close())
+
+	require.Equal(t, []Document{
+		{Index: 0, Offset: int64(strings.Index(input, `[1, 2]`)), Value: []interface{}{float64(1), float64(2)}},
+		{Index: 1, Offset: int64(strings.Index(input, `{"a"`)), Value: map[string]interface{}{"a": float64(1)}},
+	}, documents)
+}
+
+func TestWithEmbeddedJSON_PartialDocument(t *testing.T) {
+	var documents []Document
+	parser := NewIncompleteJsonParser(WithEmbeddedJSON(true), WithOnDocument(func(document Document) {
+		documents = append(documents, document)
+	}))
+
+	_, err := parser.WriteString("Here you go:\n```json\n{\"name\": \"Al")
+	require.NoError(t, err)
+	result, err := parser.GetObjects()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"name": "Al"}, result)
+	require.Empty(t, documents)
+
+	// The code block is closed before the document
+	_, err = parser.WriteString("ice\"\n```\nDone.")
+	require.NoError(t, err)
+	require.Len(t, documents, 1)
+	require.ErrorIs(t, documents[0].Err, ErrUnexpectedEnd)
+	require.Equal(t, map[string]interface{}{"name": "Alice"}, documents[0].Value)
+}
+
+func TestWithEmbeddedJSON_UnclosedAtEnd(t *testing.T) {
+	var documents []Document
+	parser := NewIncompleteJsonParser(WithEmbeddedJSON(true), WithOnDocument(func(document Document) {
+		documents = append(documents, document)
+	}))
+
+	_, err := parser.WriteString(`The answer is {"value": 4`)
+	require.NoError(t, err)
+	require.ErrorIs(t, parser.Close(), ErrUnexpectedEnd)
+	require.Len(t, documents, 1)
+	require.Equal(t, map[string]interface{}{"value": float64(4)}, documents[0].Value)
+}
+
+func TestIncompleteJsonParser_Remainder(t *testing.T) {
+	parser := NewIncompleteJsonParser(WithIgnoreExtraCharacters(true))
+	_, err := parser.WriteString(`{"a":1} Here is why.`)
+	require.NoError(t, err)
+	require.Equal(t, " Here is why.", parser.Remainder())
+	offset, ok := parser.EndOffset()
+	require.True(t, ok)
+	require.Equal(t, int64(7), offset)
+}
+
+func TestIncompleteJsonParser_RemainderAcrossChunks(t *testing.T) {
+	parser := NewIncompleteJsonParser(WithIgnoreExtraCharacters(true))
+
+	_, err := parser.WriteString(`{"a":"é"`)
+	require.NoError(t, err)
+	_, ok := parser.EndOffset()
+	require.False(t, ok)
+	require.Empty(t, parser.Remainder())
+
+	_, err = parser.WriteString("}\nExplanation")
+	require.NoError(t, err)
+	_, err = parser.WriteString(" continues")
+	require.NoError(t, err)
+
+	offset, ok := parser.EndOffset()
+	require.True(t, ok)
+	require.Equal(t, int64(len(`{"a":"é"}`)), offset)
+	require.Equal(t, "\nExplanation continues", parser.Remainder())
+
+	parser.Reset()
+	require.Empty(t, parser.Remainder())
+	_, ok = parser.EndOffset()
+	require.False(t, ok)
+}
+
+func TestIncompleteJsonParser_RemainderAfterNumber(t *testing.T) {
+	testCases := []struct {
+		input     string
+		offset    int64
+		remainder string
+	}{
+		{`42 trailing`, 2, " trailing"},
+		{`42x`, 2, "x"},
+		{`-1.5e3, then text`, 6, ", then text"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			parser := NewIncompleteJsonParser(WithIgnoreExtraCharacters(true))
+			_, err := parser.WriteString(tc.input)
+			require.NoError(t, err)
+			offset, ok := parser.EndOffset()
+			require.True(t, ok)
+			require.Equal(t, tc.offset, offset)
+			require.Equal(t, tc.remainder, parser.Remainder())
+		})
+	}
+
+	// A number at the end of the input ends when the input is closed
+	parser := NewIncompleteJsonParser()
+	_, err := parser.WriteString(`12`)
+	require.NoError(t, err)
+	_, ok := parser.EndOffset()
+	require.False(t, ok)
+	require.NoError(t, parser.Close())
+	offset, ok := parser.EndOffset()
+	require.True(t, ok)
+	require.Equal(t, int64(2), offset)
+
+	// Without WithIgnoreExtraCharacters only whitespace remains
+	parser = NewIncompleteJsonParser()
+	_, err = parser.WriteString("[1] \n")
+	require.NoError(t, err)
+	require.Equal(t, " \n", parser.Remainder())
+}
+
+func TestIncompleteJsonParser_GetCompleted(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"id": 12, "name": "Al`, map[string]interface{}{"id": float64(12)}},
+		{`{"id": 1`, map[string]interface{}{}},
+		{`{"flag": tr`, map[string]interface{}{}},
+		{`{"price": 12.`, map[string]interface{}{}},
+		{`{"na`, map[string]interface{}{}},
+		{`{"name": "Bob"`, map[string]interface{}{"name": "Bob"}},
+		{`{"items": [1, 2, {"a": "x"}, "pa`, map[string]interface{}{"items": []interface{}{float64(1), float64(2), map[string]interface{}{"a": "x"}}}},
+		{`{"nested": {"a": 1, "b": `, map[string]interface{}{"nested": map[string]interface{}{"a": float64(1)}}},
+		{`{"nested": {"a": 1, "b": [`, map[string]interface{}{"nested": map[string]interface{}{"a": float64(1), "b": []interface{}{}}}},
+		{`[tr, 1`, []interface{}{true}},
+		{`[[1, 2], [3`, []interface{}{[]interface{}{float64(1), float64(2)}, []interface{}{}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			parser := NewIncompleteJsonParser()
+			_, err := parser.WriteString(tc.input)
+			require.NoError(t, err)
+			result, err := parser.GetCompleted()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestIncompleteJsonParser_GetCompletedTopLevel(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+	_, err := parser.GetCompleted()
+	require.ErrorIs(t, err, ErrNoInput)
+
+	_, err = parser.WriteString(`12`)
+	require.NoError(t, err)
+	_, err = parser.GetCompleted()
+	require.ErrorIs(t, err, ErrNoInput)
+
+	require.NoError(t, parser.Close())
+	result, err := parser.GetCompleted()
+	require.NoError(t, err)
+	require.Equal(t, float64(12), result)
+}
+
+func TestIncompleteJsonParser_GetCompletedNeverGuesses(t *testing.T) {
+	input := `{"id": 12345, "name": "Alice", "active": true, "score": -1.5e2, "tags": ["a", "bc"], "meta": {"x": null}}`
+
+	final, err := Parse(input)
+	require.NoError(t, err)
+
+	parser := NewIncompleteJsonParser()
+	for _, letter := range input {
+		_, err := parser.WriteString(string(letter))
+		require.NoError(t, err)
+		result, err := parser.GetCompleted()
+		if err == ErrNoInput {
+			continue
+		}
+		require.NoError(t, err)
+		for key, value := range result.(map[string]interface{}) {
+			switch value := value.(type) {
+			case []interface{}:
+				expected := final.(map[string]interface{})[key].([]interface{})
+				require.Equal(t, expected[:len(value)], value)
+			case map[string]interface{}:
+				for innerKey, innerValue := range value {
+					require.Equal(t, final.(map[string]interface{})[key].(map[string]interface{})[innerKey], innerValue)
+				}
+			default:
+				require.Equal(t, final.(map[string]interface{})[key], value, key)
+			}
+		}
+	}
+}
+
+func TestWithSnapshotPolicy(t *testing.T) {
+	type User struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	var user User
+	err := UnmarshalTo(`{"id": 5, "name": "Bo`, &user, WithSnapshotPolicy(SnapshotCompleted))
+	require.NoError(t, err)
+	require.Equal(t, User{ID: 5}, user)
+
+	result, err := Parse(`{"b": 1, "a": 2, "c": "x`, WithSnapshotPolicy(SnapshotCompleted), WithOrderedObjects(true))
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "a"}, result.(*OrderedObject).Keys())
+}
+
+func TestIncompleteJsonParser_GetNodes(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+	_, err := parser.GetNodes()
+	require.ErrorIs(t, err, ErrNoInput)
+
+	_, err = parser.WriteString(`{"id": 1, "tags": ["a", "b"], "ok": tr, "na`)
+	require.NoError(t, err)
+
+	root, err := parser.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, NodeObject, root.Kind)
+	require.False(t, root.Complete)
+	require.Equal(t, Path{}, root.Path)
+	require.Len(t, root.Children, 4)
+
+	id := root.Children[0]
+	require.Equal(t, "id", id.Key)
+	require.Equal(t, NodeNumber, id.Kind)
+	require.Equal(t, float64(1), id.Value)
+	require.True(t, id.Complete)
+	require.True(t, id.KeyComplete)
+
+	tags := root.Children[1]
+	require.Equal(t, NodeArray, tags.Kind)
+	require.True(t, tags.Complete)
+	require.Equal(t, []interface{}{"a", "b"}, tags.Value)
+	require.Len(t, tags.Children, 2)
+	require.Equal(t, Path{"tags", 1}, tags.Children[1].Path)
+	require.Equal(t, NodeString, tags.Children[1].Kind)
+	require.True(t, tags.Children[1].Complete)
+
+	// Lenient mode accepted the unfinished keyword, so it is final
+	ok := root.Children[2]
+	require.Equal(t, NodeBool, ok.Kind)
+	require.Equal(t, true, ok.Value)
+	require.True(t, ok.Complete)
+
+	pending := root.Children[3]
+	require.Equal(t, "na", pending.Key)
+	require.Equal(t, NodeNull, pending.Kind)
+	require.False(t, pending.KeyComplete)
+	require.False(t, pending.Complete)
+
+	_, err = parser.WriteString(`me": "Al`)
+	require.NoError(t, err)
+	root, err = parser.GetNodes()
+	require.NoError(t, err)
+	name := root.Children[3]
+	require.Equal(t, "name", name.Key)
+	require.True(t, name.KeyComplete)
+	require.Equal(t, NodeString, name.Kind)
+	require.Equal(t, "Al", name.Value)
+	require.False(t, name.Complete)
+
+	// Complete nodes are shared between calls
+	require.Same(t, tags, root.Children[1])
+
+	_, err = parser.WriteString(`ice"}`)
+	require.NoError(t, err)
+	root, err = parser.GetNodes()
+	require.NoError(t, err)
+	require.True(t, root.Complete)
+	require.True(t, root.Children[3].Complete)
+	require.Equal(t, "Alice", root.Children[3].Value)
+}
+
+func TestIncompleteJsonParser_GetNodesArray(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+	_, err := parser.WriteString(`[null, 2.5, [true], {"a": fal`)
+	require.NoError(t, err)
+
+	root, err := parser.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, NodeArray, root.Kind)
+	require.Len(t, root.Children, 4)
+	require.Equal(t, []NodeKind{NodeNull, NodeNumber, NodeArray, NodeObject}, []NodeKind{
+		root.Children[0].Kind, root.Children[1].Kind, root.Children[2].Kind, root.Children[3].Kind,
+	})
+	require.True(t, root.Children[2].Complete)
+	require.False(t, root.Children[3].Complete)
+
+	member := root.Children[3].Children[0]
+	require.Equal(t, Path{3, "a"}, member.Path)
+	require.Equal(t, NodeBool, member.Kind)
+	require.Equal(t, false, member.Value)
+	require.False(t, member.Complete)
+	require.Equal(t, "bool", member.Kind.String())
+}
+
+func TestIncompleteJsonParser_GetNodesCollectedKeys(t *testing.T) {
+	parser := NewIncompleteJsonParser(WithDuplicateKeys(DuplicateKeysCollect))
+	for _, chunk := range []string{`{"a": 1, "a": 2`, `, "a": 3`, `}`} {
+		_, err := parser.WriteString(chunk)
+		require.NoError(t, err)
+		root, err := parser.GetNodes()
+		require.NoError(t, err)
+		result, err := parser.GetObjects()
+		require.NoError(t, err)
+		require.Equal(t, result, root.Value)
+		require.Equal(t, result.(map[string]interface{})["a"], root.Children[0].Value)
+	}
+
+	root, err := parser.GetNodes()
+	require.NoError(t, err)
+	require.Len(t, root.Children[0].Children, 3)
+	require.Equal(t, []interface{}{float64(1), float64(2), float64(3)}, root.Children[0].Value)
+	for i, child := range root.Children[0].Children {
+		require.Equal(t, Path{"a", i}, child.Path)
+		require.Equal(t, "", child.Key)
+	}
+
+	// Gathered values and their members are found at their index in the array
+	parser = NewIncompleteJsonParser(WithDuplicateKeys(DuplicateKeysCollect))
+	_, err = parser.WriteString(`{"a":{"x":1},`)
+	require.NoError(t, err)
+	_, err = parser.GetNodes()
+	require.NoError(t, err)
+	_, err = parser.WriteString(`"a":2}`)
+	require.NoError(t, err)
+	root, err = parser.GetNodes()
+	require.NoError(t, err)
+	collected := root.Children[0]
+	require.Equal(t, Path{"a"}, collected.Path)
+	require.Equal(t, "a", collected.Key)
+	require.Len(t, collected.Children, 2)
+	require.Equal(t, Path{"a", 0}, collected.Children[0].Path)
+	require.Equal(t, "", collected.Children[0].Key)
+	require.Equal(t, Path{"a", 0, "x"}, collected.Children[0].Children[0].Path)
+	require.Equal(t, Path{"a", 1}, collected.Children[1].Path)
+	require.Equal(t, "", collected.Children[1].Key)
+}
+
+func TestWithOnValueComplete(t *testing.T) {
+	var paths []string
+	var values []interface{}
+	parser := NewIncompleteJsonParser(WithOnValueComplete(func(path Path, value interface{}) {
+		paths = append(paths, path.String())
+		values = append(values, value)
+	}))
+
+	_, err := parser.WriteString(`{"name": "x", "items": [1, {"a": true}, [2]], "n": 3}`)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"/name", "/items/0", "/items/1/a", "/items/1", "/items/2/0", "/items/2", "/items", "/n", ""}, paths)
+	require.Equal(t, "x", values[0])
+	require.Equal(t, map[string]interface{}{"a": true}, values[3])
+	require.Equal(t, float64(3), values[7])
+}
+
+func TestWithOnValueComplete_FiresAsValuesEnd(t *testing.T) {
+	var completed []string
+	parser := NewIncompleteJsonParser(WithOnValueComplete(func(path Path, value interface{}) {
+		completed = append(completed, path.String())
+	}))
+
+	_, err := parser.WriteString(`{"arguments": {"query": "go`)
+	require.NoError(t, err)
+	require.Empty(t, completed)
+
+	_, err = parser.WriteString(`"`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/arguments/query"}, completed)
+
+	// A number is only final once a delimiter follows
+	_, err = parser.WriteString(`, "limit": 10`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/arguments/query"}, completed)
+
+	_, err = parser.WriteString(`}`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/arguments/query", "/arguments/limit", "/arguments"}, completed)
+}
+
+func TestWithOnValueComplete_TopLevelNumber(t *testing.T) {
+	var values []interface{}
+	parser := NewIncompleteJsonParser(WithOnValueComplete(func(path Path, value interface{}) {
+		require.Equal(t, Path{}, path)
+		values = append(values, value)
+	}))
+
+	_, err := parser.WriteString(`42`)
+	require.NoError(t, err)
+	require.Empty(t, values)
+	require.NoError(t, parser.Close())
+	require.Equal(t, []interface{}{float64(42)}, values)
+}
+
+func TestWithOnEvent(t *testing.T) {
+	var events []Event
+	parser := NewIncompleteJsonParser(WithOnEvent(func(event Event) {
+		events = append(events, event)
+	}))
+
+	_, err := parser.WriteString(`{"a": "hello", "b": [1, true, null], "c": {}}`)
+	require.NoError(t, err)
+
+	require.Equal(t, []Event{
+		{Kind: EventBeginObject, Path: Path{}, Complete: true},
+		{Kind: EventKey, Path: Path{"a"}, Value: "a", Complete: true},
+		{Kind: EventString, Path: Path{"a"}, Value: "hello", Complete: true},
+		{Kind: EventKey, Path: Path{"b"}, Value: "b", Complete: true},
+		{Kind: EventBeginArray, Path: Path{"b"}, Complete: true},
+		{Kind: EventNumber, Path: Path{"b", 0}, Value: float64(1), Complete: true},
+		{Kind: EventBool, Path: Path{"b", 1}, Value: true, Complete: true},
+		{Kind: EventNull, Path: Path{"b", 2}, Complete: true},
+		{Kind: EventEndArray, Path: Path{"b"}, Complete: true},
+		{Kind: EventKey, Path: Path{"c"}, Value: "c", Complete: true},
+		{Kind: EventBeginObject, Path: Path{"c"}, Complete: true},
+		{Kind: EventEndObject, Path: Path{"c"}, Complete: true},
+		{Kind: EventEndObject, Path: Path{}, Complete: true},
+	}, events)
+
+	// Events alone never build the snapshot tree
+	require.Nil(t, parser.scope.(*ObjectScope).snapshot)
+}
+
+func TestWithOnEvent_StringFragments(t *testing.T) {
+	var events []Event
+	parser := NewIncompleteJsonParser(WithOnEvent(func(event Event) {
+		if event.Kind == EventString {
+			events = append(events, event)
+		}
+	}))
+
+	for _, chunk := range []string{`{"text": "Hel`, `lo \"w`, `orld\u00`, `e9"`, `, "empty": ""}`} {
+		_, err := parser.WriteString(chunk)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []Event{
+		{Kind: EventString, Path: Path{"text"}, Value: "Hel"},
+		{Kind: EventString, Path: Path{"text"}, Value: `lo "w`},
+		{Kind: EventString, Path: Path{"text"}, Value: "orld"},
+		{Kind: EventString, Path: Path{"text"}, Value: "é", Complete: true},
+		{Kind: EventString, Path: Path{"empty"}, Value: "", Complete: true},
+	}, events)
+}
+
+func TestWithOnEvent_NumberModes(t *testing.T) {
+	var events []Event
+	parser := NewIncompleteJsonParser(WithUseNumber(true), WithOnEvent(func(event Event) {
+		events = append(events, event)
+	}))
+
+	_, err := parser.WriteString(`[12345678901234567891`)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	_, err = parser.WriteString(`]`)
+	require.NoError(t, err)
+	require.Equal(t, Event{Kind: EventNumber, Path: Path{0}, Value: json.Number("12345678901234567891"), Complete: true}, events[1])
+	require.Equal(t, EventEndArray, events[2].Kind)
+	require.Equal(t, "EndArray", events[2].Kind.String())
+}
+
+// applyPatch applies JSON Patch operations, including the "append" extension, to a decoded document
+func applyPatch(t *testing.T, document interface{}, ops []PatchOperation) interface{} {
+	for _, op := range ops {
+		if op.Path == "" {
+			document = op.Value
+			continue
+		}
+		tokens := strings.Split(op.Path[1:], "/")
+		for i := range tokens {
+			tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(tokens[i])
+		}
+
+		var parent interface{} = document
+		for _, token := range tokens[:len(tokens)-1] {
+			switch container := parent.(type) {
+			case map[string]interface{}:
+				parent = container[token]
+			case []interface{}:
+				index, err := strconv.Atoi(token)
+				require.NoError(t, err)
+				parent = container[index]
+			}
+		}
+
+		last := tokens[len(tokens)-1]
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			switch op.Op {
+			case "add", "replace":
+				container[last] = op.Value
+			case "remove":
+				delete(container, last)
+			case "append":
+				container[last] = container[last].(string) + op.Value.(string)
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(last)
+			require.NoError(t, err)
+			switch op.Op {
+			case "add":
+				require.Equal(t, len(container), index)
+				container = append(container, op.Value)
+				// Arrays only grow at the end; store the longer slice in the grandparent
+				document = replaceAt(document, tokens[:len(tokens)-1], container)
+			case "replace":
+				container[index] = op.Value
+			case "append":
+				container[index] = container[index].(string) + op.Value.(string)
+			}
+		}
+	}
+	return document
+}
+
+// replaceAt stores value at the path given by tokens
+func replaceAt(document interface{}, tokens []string, value interface{}) interface{} {
+	if len(tokens) == 0 {
+		return value
+	}
+	switch container := document.(type) {
+	case map[string]interface{}:
+		container[tokens[0]] = replaceAt(container[tokens[0]], tokens[1:], value)
+	case []interface{}:
+		index, _ := strconv.Atoi(tokens[0])
+		container[index] = replaceAt(container[index], tokens[1:], value)
+	}
+	return document
+}
+
+func TestIncompleteJsonParser_Diff(t *testing.T) {
+	input := `{"title": "Hello \"world\"", "count": 12345, "ok": true, "tags": ["a", "bc", {"x/y": null}], "nested": {"list": [[1], [2.5e1]], "empty": {}}, "last": "é"}`
+
+	parser := NewIncompleteJsonParser()
+	_, err := parser.Diff()
+	require.ErrorIs(t, err, ErrNoInput)
+
+	var document interface{}
+	var seq uint64
+	for _, letter := range input {
+		_, err := parser.WriteString(string(letter))
+		require.NoError(t, err)
+
+		patch, err := parser.Diff()
+		if err == ErrNoInput {
+			continue
+		}
+		require.NoError(t, err)
+		if len(patch.Operations) > 0 {
+			seq++
+		}
+		require.Equal(t, seq, patch.Seq)
+
+		document = applyPatch(t, document, patch.Operations)
+		expected, err := parser.GetObjects()
+		require.NoError(t, err)
+		require.Equal(t, expected, document)
+	}
+}
+
+func TestIncompleteJsonParser_DiffOperations(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+
+	_, err := parser.WriteString(`{"text": "Hel`)
+	require.NoError(t, err)
+	patch, err := parser.Diff()
+	require.NoError(t, err)
+	require.Equal(t, Patch{Seq: 1, Operations: []PatchOperation{
+		{Op: "add", Path: "", Value: map[string]interface{}{"text": "Hel"}},
+	}}, patch)
+
+	_, err = parser.WriteString(`lo", "items": [1`)
+	require.NoError(t, err)
+	patch, err = parser.Diff()
+	require.NoError(t, err)
+	require.Equal(t, Patch{Seq: 2, Operations: []PatchOperation{
+		{Op: "append", Path: "/text", Value: "lo"},
+		{Op: "add", Path: "/items", Value: []interface{}{float64(1)}},
+	}}, patch)
+
+	_, err = parser.WriteString(`2, 3], "na`)
+	require.NoError(t, err)
+	patch, err = parser.Diff()
+	require.NoError(t, err)
+	require.Equal(t, Patch{Seq: 3, Operations: []PatchOperation{
+		{Op: "replace", Path: "/items/0", Value: float64(12)},
+		{Op: "add", Path: "/items/1", Value: float64(3)},
+		{Op: "add", Path: "/na", Value: nil},
+	}}, patch)
+
+	_, err = parser.WriteString(`me": "x"}`)
+	require.NoError(t, err)
+	patch, err = parser.Diff()
+	require.NoError(t, err)
+	require.Equal(t, Patch{Seq: 4, Operations: []PatchOperation{
+		{Op: "remove", Path: "/na"},
+		{Op: "add", Path: "/name", Value: "x"},
+	}}, patch)
+
+	encoded, err := json.Marshal(patch)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"seq":4,"ops":[{"op":"remove","path":"/na"},{"op":"add","path":"/name","value":"x"}]}`, string(encoded))
+
+	// Nothing changed
+	patch, err = parser.Diff()
+	require.NoError(t, err)
+	require.Equal(t, Patch{Seq: 4}, patch)
+}
+
+func TestIncompleteJsonParser_DiffDuplicateKeys(t *testing.T) {
+	input := `{"ab": 1, "a": 2, "a": "xy", "abc": [3], "a": {"b": 4}, "ab": 5}`
+	for _, policy := range []DuplicateKeyPolicy{DuplicateKeysLastWins, DuplicateKeysFirstWins, DuplicateKeysCollect} {
+		parser := NewIncompleteJsonParser(WithDuplicateKeys(policy))
+		var document interface{}
+		for i, letter := range input {
+			_, err := parser.WriteString(string(letter))
+			require.NoError(t, err)
+
+			patch, err := parser.Diff()
+			require.NoError(t, err)
+			document = applyPatch(t, document, patch.Operations)
+			expected, err := parser.GetObjects()
+			require.NoError(t, err)
+			require.Equal(t, expected, document, "policy %d after %q", policy, input[:i+1])
+		}
+	}
+}
+
+func TestIncompleteJsonParser_DiffMultipleDocuments(t *testing.T) {
+	parser := NewIncompleteJsonParser(WithMultipleDocuments(true))
+
+	_, err := parser.WriteString(`{"a": 1}`)
+	require.NoError(t, err)
+	_, err = parser.Diff()
+	require.NoError(t, err)
+
+	_, err = parser.WriteString(` [2`)
+	require.NoError(t, err)
+	patch, err := parser.Diff()
+	require.NoError(t, err)
+	require.Equal(t, []PatchOperation{{Op: "replace", Path: "", Value: []interface{}{float64(2)}}}, patch.Operations)
+}
+
+func TestIncompleteJsonParser_Subscribe(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+	answer := parser.Subscribe(Path{"answer"})
+	other := parser.Subscribe(Path{"items", 1})
+
+	var deltas []string
+	for _, chunk := range []string{`{"id": "x", "answer": "Hel`, `lo\`, `nw\u00`, `e9rld \ud83d`, `\ude00!", `, `"items": ["a", "b\"c"]}`} {
+		_, err := parser.WriteString(chunk)
+		require.NoError(t, err)
+		deltas = append(deltas, answer.Delta())
+	}
+	require.Equal(t, []string{"Hel", "lo", "\nw", "érld ", "\U0001F600!", ""}, deltas)
+	require.True(t, answer.Complete())
+	require.Equal(t, `b"c`, other.Delta())
+	require.True(t, other.Complete())
+	require.Equal(t, "", other.Delta())
+}
+
+func TestIncompleteJsonParser_SubscribeLate(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+	_, err := parser.WriteString(`{"a": "done", "b": "par`)
+	require.NoError(t, err)
+
+	done := parser.Subscribe(Path{"a"})
+	require.True(t, done.Complete())
+	require.Equal(t, "done", done.Delta())
+
+	partial := parser.Subscribe(Path{"b"})
+	require.False(t, partial.Complete())
+	_, err = parser.WriteString(`tial`)
+	require.NoError(t, err)
+	require.Equal(t, "partial", partial.Delta())
+
+	// A later subscription catches up with the text decoded so far
+	again := parser.Subscribe(Path{"b"})
+	_, err = parser.WriteString(`ly"}`)
+	require.NoError(t, err)
+	require.Equal(t, "partially", again.Delta())
+	require.Equal(t, "ly", partial.Delta())
+	require.True(t, again.Complete())
+
+	// Values that are not strings are ignored
+	numbers := NewIncompleteJsonParser()
+	number := numbers.Subscribe(Path{"n"})
+	_, err = numbers.WriteString(`{"n": 12}`)
+	require.NoError(t, err)
+	require.Equal(t, "", number.Delta())
+	require.False(t, number.Complete())
+}
+
+func TestIncompleteJsonParser_SubscribeMidString(t *testing.T) {
+	var fragments []string
+	parser := NewIncompleteJsonParser(WithOnEvent(func(event Event) {
+		if event.Kind == EventString {
+			fragments = append(fragments, event.Value.(string))
+		}
+	}))
+	_, err := parser.WriteString(`{"answer":"hello wor`)
+	require.NoError(t, err)
+	_, err = parser.WriteString(`l`)
+	require.NoError(t, err)
+
+	// The text decoded before the call is available at once, even with no more input
+	answer := parser.Subscribe(Path{"answer"})
+	require.Equal(t, "hello worl", answer.Delta())
+	require.Equal(t, "", answer.Delta())
+
+	_, err = parser.WriteString(`d"}`)
+	require.NoError(t, err)
+	require.Equal(t, "d", answer.Delta())
+	require.True(t, answer.Complete())
+	// Observers still receive every fragment
+	require.Equal(t, []string{"hello wor", "l", "d"}, fragments)
+}
+
+func TestIncompleteJsonParser_SubscriptionRelease(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+	_, err := parser.WriteString(`{"a": "x`)
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		parser.Subscribe(Path{"a"}).Close()
+	}
+	require.Empty(t, parser.scopeContext.subscriptions)
+
+	closed := parser.Subscribe(Path{"a"})
+	open := parser.Subscribe(Path{"a"})
+	closed.Close()
+	_, err = parser.WriteString(`y`)
+	require.NoError(t, err)
+	require.Equal(t, "x", closed.Delta())
+	require.Equal(t, "xy", open.Delta())
+	require.Len(t, parser.scopeContext.subscriptions, 1)
+
+	// Completed subscriptions are dropped
+	_, err = parser.WriteString(`"}`)
+	require.NoError(t, err)
+	require.True(t, open.Complete())
+	require.Empty(t, parser.scopeContext.subscriptions)
+	open.Close()
+}
+
+func TestIncompleteJsonParser_Get(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+
+	result, err := parser.Get("/tool_calls/0/arguments/query")
+	require.NoError(t, err)
+	require.False(t, result.Exists)
+
+	_, err = parser.WriteString(`{"tool_calls": [{"id": 7, "arguments": {"query": "weather in`)
+	require.NoError(t, err)
+
+	result, err = parser.Get("/tool_calls/0/arguments/query")
+	require.NoError(t, err)
+	require.Equal(t, Result{Path: Path{"tool_calls", 0, "arguments", "query"}, Value: "weather in", Exists: true}, result)
+
+	result, err = parser.Get("/tool_calls/0/id")
+	require.NoError(t, err)
+	require.True(t, result.Exists)
+	require.True(t, result.Complete)
+	require.Equal(t, float64(7), result.Value)
+
+	result, err = parser.Get("/tool_calls/1")
+	require.NoError(t, err)
+	require.False(t, result.Exists)
+
+	_, err = parser.WriteString(` Paris"}}, {"a/b": {"~": [1, 2`)
+	require.NoError(t, err)
+
+	query, result, err := GetAs[string](parser, "/tool_calls/0/arguments/query")
+	require.NoError(t, err)
+	require.Equal(t, "weather in Paris", query)
+	require.True(t, result.Complete)
+
+	numbers, result, err := GetAs[[]int](parser, "/tool_calls/1/a~1b/~0")
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, numbers)
+	require.False(t, result.Complete)
+
+	// The element the array has moved past is final, the one being written is not
+	result, err = parser.Get("/tool_calls/1/a~1b/~0/0")
+	require.NoError(t, err)
+	require.True(t, result.Complete)
+	result, err = parser.Get("/tool_calls/1/a~1b/~0/1")
+	require.NoError(t, err)
+	require.False(t, result.Complete)
+
+	result, err = parser.Get("")
+	require.NoError(t, err)
+	require.Equal(t, Path{}, result.Path)
+	require.False(t, result.Complete)
+
+	for _, pointer := range []string{"/tool_calls/01", "/tool_calls/-", "/tool_calls/x", "/x"} {
+		result, err = parser.Get(pointer)
+		require.NoError(t, err)
+		require.False(t, result.Exists, pointer)
+	}
+
+	var queryErr *QueryError
+	_, err = parser.Get("tool_calls")
+	require.ErrorAs(t, err, &queryErr)
+	_, err = parser.Get("/a~2")
+	require.ErrorAs(t, err, &queryErr)
+	require.Equal(t, 2, queryErr.Offset)
+}
+
+func TestIncompleteJsonParser_Query(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+	_, err := parser.WriteString(`{"store": {"book": [
+		{"title": "A", "price": 8.95, "tags": ["x"]},
+		{"title": "B", "price": 22.99},
+		{"title": "C", "price": 12, "isbn": "0-553"},
+		{"title": "D", "pri`)
+	require.NoError(t, err)
+
+	paths := func(query string) []string {
+		results, err := parser.Query(query)
+		require.NoError(t, err)
+		var paths []string
+		for _, result := range results {
+			paths = append(paths, result.Path.String())
+		}
+		return paths
+	}
+
+	require.Equal(t, []string{"/store/book/0/title", "/store/book/1/title", "/store/book/2/title", "/store/book/3/title"}, paths("$.store.book[*].title"))
+	require.Equal(t, []string{"/store/book/0/title", "/store/book/1/title", "/store/book/2/title", "/store/book/3/title"}, paths("$..title"))
+	require.Equal(t, []string{"/store/book/3"}, paths("$.store.book[-1]"))
+	require.Equal(t, []string{"/store/book/0/price", "/store/book/0/title", "/store/book/1/price", "/store/book/1/title"}, paths(`$["store"]['book'][0,1]['price', "title"]`))
+	require.Equal(t, []string{"/store/book/0", "/store/book/2"}, paths("$.store.book[?@.price < 20]"))
+	require.Equal(t, []string{"/store/book/1"}, paths("$.store.book[?(@.price > 20 || @.title == 'Z')]"))
+	require.Equal(t, []string{"/store/book/2"}, paths("$.store.book[?@.isbn && !@.tags]"))
+	require.Equal(t, []string{"/store/book/0/tags/0"}, paths(`$..tags[?@ == "x"]`))
+
+	// The last book may still get an isbn, so it is neither selected nor excluded yet
+	require.Equal(t, []string{"/store/book/0", "/store/book/1"}, paths("$.store.book[?!@.isbn]"))
+	_, err = parser.WriteString(`ce": 1}]}}`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/store/book/0", "/store/book/1", "/store/book/3"}, paths("$.store.book[?!@.isbn]"))
+
+	results, err := parser.Query("$.store.book[?@.title == 'D'].price")
+	require.NoError(t, err)
+	require.Equal(t, []Result{{Path: Path{"store", "book", 3, "price"}, Value: float64(1), Exists: true, Complete: true}}, results)
+
+	var queryErr *QueryError
+	for _, query := range []string{"store", "$.", "$[", "$[?@..a]", "$[?@.a <]", "$['a"} {
+		_, err = parser.Query(query)
+		require.ErrorAs(t, err, &queryErr, query)
+	}
+}
+
+func TestWithSchema_EarlyViolations(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"properties": {
+			"status": {"enum": ["pending", "done"]},
+			"tags": {"type": "array", "maxItems": 2, "items": {"type": "string", "maxLength": 5}},
+			"count": {"type": "integer", "minimum": 0},
+			"child": {"$ref": "#"}
+		},
+		"required": ["status"],
+		"additionalProperties": false
+	}`)
+
+	tests := []struct {
+		name    string
+		chunks  []string
+		path    Path
+		keyword string
+	}{
+		{"wrong type starts", []string{`[`}, nil, "type"},
+		{"wrong member type starts", []string{`{"status": "pending", "count": "`}, Path{"count"}, "type"},
+		{"enum prefix", []string{`{"status": "do`, `ing`}, Path{"status"}, "enum"},
+		{"max items", []string{`{"tags": ["a", "b", "`}, Path{"tags"}, "maxItems"},
+		{"max length", []string{`{"tags": ["abcdef`}, Path{"tags", 0}, "maxLength"},
+		{"unknown property", []string{`{"status": "done", "extra"`}, Path{"extra"}, "additionalProperties"},
+		{"nested by reference", []string{`{"child": {"child": {"bogus"`}, Path{"child", "child", "bogus"}, "additionalProperties"},
+		{"integer", []string{`{"count": 1.5,`}, Path{"count"}, "type"},
+		{"minimum", []string{`{"count": -1}`}, Path{"count"}, "minimum"},
+		{"required", []string{`{"tags": []}`}, nil, "required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewIncompleteJsonParser(WithSchema(schema))
+			var err error
+			for i, chunk := range tt.chunks {
+				_, err = parser.WriteString(chunk)
+				if i < len(tt.chunks)-1 {
+					require.NoError(t, err)
+				}
+			}
+			var schemaErr *SchemaError
+			require.ErrorAs(t, err, &schemaErr)
+			require.Equal(t, tt.keyword, schemaErr.Keyword)
+			require.Equal(t, tt.path.String(), schemaErr.Path.String())
+
+			// The violation stops the parser
+			_, err = parser.WriteString(` `)
+			require.ErrorAs(t, err, &schemaErr)
+		})
+	}
+}
+
+func TestWithSchema_Valid(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+			"kind": {"const": "tool"},
+			"args": {"type": "array", "prefixItems": [{"type": "number"}], "items": {"type": "boolean"}, "minItems": 2},
+			"value": {"anyOf": [{"type": "string"}, {"type": "number", "multipleOf": 0.5}]}
+		},
+		"patternProperties": {"^x-": {"type": "null"}},
+		"additionalProperties": false
+	}`)
+	input := `{"name": "search", "kind": "tool", "args": [1, true, false], "value": 2.5, "x-a": null}`
+
+	parser := NewIncompleteJsonParser(WithSchema(schema))
+	for _, r := range input {
+		_, err := parser.WriteString(string(r))
+		require.NoError(t, err)
+	}
+	require.NoError(t, parser.Close())
+
+	parser = NewIncompleteJsonParser(WithSchema(schema))
+	_, err := parser.WriteString(`{"value": tr`)
+	var schemaErr *SchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	require.Equal(t, "anyOf", schemaErr.Keyword)
+
+	parser = NewIncompleteJsonParser(WithSchema(schema))
+	_, err = parser.WriteString(`{"args": [1, true]`)
+	require.NoError(t, err)
+	_, err = parser.WriteString(`}`)
+	require.NoError(t, err)
+	parser = NewIncompleteJsonParser(WithSchema(schema))
+	_, err = parser.WriteString(`{"args": [1]}`)
+	require.ErrorAs(t, err, &schemaErr)
+	require.Equal(t, "minItems", schemaErr.Keyword)
+
+	parser = NewIncompleteJsonParser(WithSchema([]byte(`{"type": `)))
+	_, err = parser.WriteString(`{}`)
+	require.Error(t, err)
+	parser = NewIncompleteJsonParser(WithSchema([]byte(`{"$ref": "#/$defs/missing"}`)))
+	_, err = parser.WriteString(`{}`)
+	require.ErrorContains(t, err, "unsupported $ref")
+}
+
+func TestWithSchema_CollectedKeys(t *testing.T) {
+	schema := []byte(`{"properties": {"a": {"type": "array", "maxItems": 2}}}`)
+	parser := NewIncompleteJsonParser(WithSchema(schema), WithDuplicateKeys(DuplicateKeysCollect))
+	_, err := parser.WriteString(`{"a": 1, "a": 2, `)
+	require.NoError(t, err)
+	_, err = parser.WriteString(`"a": 3, `)
+	var schemaErr *SchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	require.Equal(t, "maxItems", schemaErr.Keyword)
+}
+
+func TestWithSchema_MultipleDocuments(t *testing.T) {
+	var documents []Document
+	parser := NewIncompleteJsonParser(
+		WithSchema([]byte(`{"type": "object", "required": ["id"]}`)),
+		WithNDJSON(true),
+		WithOnDocument(func(document Document) {
+			documents = append(documents, document)
+		}),
+	)
+	_, err := parser.WriteString("{\"id\": 1}\n{}\n{\"id\": 2}\n")
+	require.NoError(t, err)
+	require.Len(t, documents, 3)
+	require.NoError(t, documents[0].Err)
+	var schemaErr *SchemaError
+	require.ErrorAs(t, documents[1].Err, &schemaErr)
+	require.Equal(t, "required", schemaErr.Keyword)
+	require.NoError(t, documents[2].Err)
+}
+
+func TestUnmarshalTo_TypeGuidedAssumptions(t *testing.T) {
+	type Item struct {
+		ID    int       `json:"id"`
+		Count int       `json:"count,string"`
+		When  time.Time `json:"when"`
+	}
+	type Target struct {
+		Name  string  `json:"name"`
+		Age   int     `json:"age"`
+		Score float64 `json:"score"`
+		Items []Item  `json:"items"`
+		Tags  map[string]uint8
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected Target
+		skipped  []string
+	}{
+		{"partial string for int", `{"name": "Al", "age": "3`, Target{Name: "Al"}, []string{"/age"}},
+		{"partial number with dot", `{"age": 12.`, Target{Age: 12}, nil},
+		{"partial exponent", `{"age": 1e`, Target{Age: 1}, nil},
+		{"partial fraction for int", `{"name": "x", "age": 12.5`, Target{Name: "x"}, []string{"/age"}},
+		{"partial object for number", `{"score": {"a": 1`, Target{}, []string{"/score"}},
+		{"partial time", `{"items": [{"id": 1, "when": "2024-01`, Target{Items: []Item{{ID: 1}}}, []string{"/items/0/when"}},
+		{"quoted number", `{"items": [{"count": "4`, Target{Items: []Item{{Count: 4}}}, nil},
+		{"partial quoted number", `{"items": [{"count": "4.`, Target{Items: []Item{{}}}, []string{"/items/0/count"}},
+		{"overflow", `{"Tags": {"a": 1, "b": 300`, Target{Tags: map[string]uint8{"a": 1, "b": 0}}, []string{"/Tags/b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var skipped []string
+			result, err := ParseAs[Target](tt.input, WithOnSkippedValue(func(value SkippedValue) {
+				skipped = append(skipped, value.Path.String())
+				require.Error(t, value.Err)
+			}))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+			require.Equal(t, tt.skipped, skipped)
+		})
+	}
+
+	// Complete values that do not fit the type still fail
+	_, err := ParseAs[Target](`{"age": "3", `)
+	var typeErr *json.UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+}
+
+type upperText string
+
+func (u *upperText) UnmarshalText(text []byte) error {
+	*u = upperText(strings.ToUpper(string(text)))
+	return nil
+}
+
+func TestUnmarshalTo_DirectDecode(t *testing.T) {
+	type Base struct {
+		ID   int    `json:"id"`
+		Kind string `json:"kind"`
+	}
+	type Extra struct {
+		Note string `json:"note"`
+	}
+	type Target struct {
+		Base
+		*Extra
+		Kind    string            `json:"type"`
+		Secret  string            `json:"-"`
+		Count   int64             `json:"count,string"`
+		Label   upperText         `json:"label"`
+		Amount  json.Number       `json:"amount"`
+		Data    []byte            `json:"data"`
+		Scores  map[int]float64   `json:"scores"`
+		Tags    []string          `json:"tags,omitempty"`
+		Attrs   map[string]string `json:"attrs"`
+		Ignored string
+	}
+
+	const document = `{"id": 7, "kind": "base", "note": "hi", "type": "outer", "-": "x", "Secret": "s", ` +
+		`"count": "42", "label": "abc", "amount": 1.50, "data": "aGVsbG8=", "scores": {"1": 0.5, "2": 1}, ` +
+		`"tags": [], "attrs": {"a": "b"}, "ignored": "yes"}`
+	parser := NewIncompleteJsonParser()
+	_, err := parser.WriteString(document)
+	require.NoError(t, err)
+
+	var target Target
+	require.NoError(t, parser.UnmarshalTo(&target))
+	require.Equal(t, 7, target.ID)
+	require.Equal(t, "base", target.Base.Kind)
+	require.NotNil(t, target.Extra)
+	require.Equal(t, "hi", target.Note)
+	require.Equal(t, "outer", target.Kind)
+	require.Empty(t, target.Secret)
+	require.Equal(t, int64(42), target.Count)
+	require.Equal(t, upperText("ABC"), target.Label)
+	require.Equal(t, json.Number("1.50"), target.Amount)
+	require.Equal(t, []byte("hello"), target.Data)
+	require.Equal(t, map[int]float64{1: 0.5, 2: 1}, target.Scores)
+	require.NotNil(t, target.Tags)
+	require.Empty(t, target.Tags)
+	require.Equal(t, map[string]string{"a": "b"}, target.Attrs)
+	require.Equal(t, "yes", target.Ignored)
+
+	// The result matches encoding/json for the complete document
+	var expected Target
+	require.NoError(t, json.Unmarshal([]byte(document), &expected))
+	require.Equal(t, expected, target)
+
+	// Field plans are computed once per type
+	require.Same(t, cachedFields(reflect.TypeOf(Target{})), cachedFields(reflect.TypeOf(Target{})))
+}
+
+func TestUnmarshalTo_EmbeddedUnexportedPointer(t *testing.T) {
+	type inner struct {
+		X int `json:"x"`
+	}
+	type Outer struct {
+		*inner
+		Name string `json:"name"`
+	}
+
+	const document = `{"x": 1, "name": "a"}`
+	var actual, expected Outer
+	err := UnmarshalTo(document, &actual)
+	require.Error(t, json.Unmarshal([]byte(document), &expected))
+	require.ErrorContains(t, err, "cannot set embedded pointer to unexported struct")
+	// The other fields are still decoded
+	require.Equal(t, "a", actual.Name)
+	require.Equal(t, expected, actual)
+}
+
+func TestUnmarshalTo_DirectDecodePartial(t *testing.T) {
+	type Target struct {
+		Name  string                 `json:"name"`
+		Items []int                  `json:"items"`
+		Meta  map[string]interface{} `json:"meta"`
+	}
+
+	parser := NewIncompleteJsonParser()
+	_, err := parser.WriteString(`{"name": "Ali`)
+	require.NoError(t, err)
+	var target Target
+	require.NoError(t, parser.UnmarshalTo(&target))
+	require.Equal(t, Target{Name: "Ali"}, target)
+
+	_, err = parser.WriteString(`ce", "items": [1, 2, 3`)
+	require.NoError(t, err)
+	target = Target{}
+	require.NoError(t, parser.UnmarshalTo(&target))
+	require.Equal(t, Target{Name: "Alice", Items: []int{1, 2, 3}}, target)
+
+	_, err = parser.WriteString(`], "meta": {"ok": tr`)
+	require.NoError(t, err)
+	target = Target{}
+	require.NoError(t, parser.UnmarshalTo(&target))
+	require.Equal(t, map[string]interface{}{"ok": true}, target.Meta)
+}
+
+func TestUnmarshalTo_TypeErrorNamesStruct(t *testing.T) {
+	type Address struct {
+		Zip int `json:"zip"`
+	}
+	type Person struct {
+		Age     int     `json:"age"`
+		Address Address `json:"address"`
+	}
+
+	var person Person
+	err := UnmarshalTo(`{"age": "ten"}`, &person)
+	require.EqualError(t, err, "json: cannot unmarshal string into Go struct field Person.age of type int")
+	require.EqualError(t, json.Unmarshal([]byte(`{"age": "ten"}`), &person), err.Error())
+
+	// The struct holding the field is named
+	err = UnmarshalTo(`{"address": {"zip": "abc"}}`, &person)
+	var typeErr *json.UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+	require.Equal(t, "Address", typeErr.Struct)
+	require.Equal(t, "address.zip", typeErr.Field)
+}
+
+func TestIncompleteJsonParser_Bind(t *testing.T) {
+	type Step struct {
+		Title string `json:"title"`
+		Done  bool   `json:"done"`
+	}
+	type Plan struct {
+		Name  string                 `json:"name"`
+		Owner *Step                  `json:"owner"`
+		Steps []Step                 `json:"steps"`
+		Meta  map[string]int         `json:"meta"`
+		Extra map[string]interface{} `json:"extra"`
+		Total int                    `json:"total"`
+	}
+
+	parser := NewIncompleteJsonParser()
+	var plan Plan
+	require.NoError(t, parser.Bind(&plan))
+	require.Equal(t, Plan{}, plan)
+
+	write := func(chunk string) {
+		t.Helper()
+		_, err := parser.WriteString(chunk)
+		require.NoError(t, err)
+	}
+
+	write(`{"name": "Tri`)
+	require.Equal(t, "Tri", plan.Name)
+
+	write(`p", "owner": {"title": "Al`)
+	owner := plan.Owner
+	require.NotNil(t, owner)
+	require.Equal(t, "Al", owner.Title)
+
+	write(`ice"}, "steps": [{"title": "Pack"`)
+	require.Same(t, owner, plan.Owner)
+	require.Equal(t, "Alice", owner.Title)
+	require.Equal(t, []Step{{Title: "Pack"}}, plan.Steps)
+
+	write(`}, {"title": "Go", "done": true}, {"title": "Ret`)
+	require.Equal(t, []Step{{Title: "Pack"}, {Title: "Go", Done: true}, {Title: "Ret"}}, plan.Steps)
+	steps := plan.Steps
+
+	write(`urn"}], "meta": {"a": 1`)
+	require.Equal(t, "Return", steps[2].Title)
+	meta := plan.Meta
+	require.Equal(t, map[string]int{"a": 1}, meta)
+
+	write(`, "b": 2}, "extra": {"x": {"y": 1`)
+	require.Equal(t, map[string]int{"a": 1, "b": 2}, meta)
+	extra := plan.Extra
+	nested := reflect.ValueOf(extra["x"]).Pointer()
+	write(`, "w": 2}, "z": true}, "total": 1`)
+	require.Equal(t, map[string]interface{}{"x": map[string]interface{}{"y": float64(1), "w": float64(2)}, "z": true}, extra)
+	require.Equal(t, nested, reflect.ValueOf(plan.Extra["x"]).Pointer())
+	require.Equal(t, 1, plan.Total)
+
+	write(`2}`)
+	require.Equal(t, 12, plan.Total)
+	require.Same(t, owner, plan.Owner)
+	require.NoError(t, parser.Close())
+
+	var expected Plan
+	require.NoError(t, parser.UnmarshalTo(&expected))
+	require.Equal(t, expected, plan)
+
+	// A new document starts over from the zero value
+	parser.Reset()
+	write(`{"total": 3`)
+	require.Equal(t, Plan{Total: 3}, plan)
+
+	// Close completes a number at the end of the input
+	var number float64
+	parser = NewIncompleteJsonParser(WithSnapshotPolicy(SnapshotCompleted))
+	require.NoError(t, parser.Bind(&number))
+	write(`4.5`)
+	require.Zero(t, number)
+	require.NoError(t, parser.Close())
+	require.Equal(t, 4.5, number)
+
+	// Complete values that do not fit the type are reported by Write
+	var target struct {
+		Total int `json:"total"`
+	}
+	parser = NewIncompleteJsonParser()
+	require.NoError(t, parser.Bind(&target))
+	_, err := parser.WriteString(`{"total": "3", `)
+	var typeErr *json.UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+
+	// A guess is dropped once the value can no longer be converted
+	var guess struct {
+		N int `json:"n"`
+	}
+	parser = NewIncompleteJsonParser()
+	require.NoError(t, parser.Bind(&guess))
+	write(`{"n": 12`)
+	require.Equal(t, 12, guess.N)
+	write(`.5`)
+	require.Zero(t, guess.N)
+	_, err = parser.WriteString(`, "s": "4`)
+	require.ErrorAs(t, err, &typeErr)
+	require.Zero(t, guess.N)
+	write(`2"}`)
+	require.Zero(t, guess.N)
+	require.Error(t, parser.UnmarshalTo(&guess))
+	require.Zero(t, guess.N)
+
+	require.Error(t, parser.Bind(target))
+}
+
+func TestIncompleteJsonParser_BindInterface(t *testing.T) {
+	parser := NewIncompleteJsonParser()
+	var bound interface{}
+	require.NoError(t, parser.Bind(&bound))
+
+	write := func(chunk string) {
+		t.Helper()
+		_, err := parser.WriteString(chunk)
+		require.NoError(t, err)
+	}
+	pointer := func(v interface{}) uintptr {
+		return reflect.ValueOf(v).Pointer()
+	}
+
+	write(`{"items": [{"a": 1`)
+	root := bound.(map[string]interface{})
+	items := root["items"].([]interface{})
+	first := items[0].(map[string]interface{})
+
+	write(`, "b": 2}, [1`)
+	require.Equal(t, pointer(root), pointer(bound))
+	require.Equal(t, pointer(first), pointer(root["items"].([]interface{})[0]))
+	require.Equal(t, map[string]interface{}{"a": float64(1), "b": float64(2)}, first)
+
+	// Slices are appended to, and the maps they hold stay the same
+	write(`, 2], {"c": 3}]`)
+	items = root["items"].([]interface{})
+	require.Equal(t, pointer(first), pointer(items[0]))
+	require.Equal(t, []interface{}{float64(1), float64(2)}, items[1])
+	last := items[2].(map[string]interface{})
+	write(`, "d": {"e": [{"f": 1}`)
+	require.Equal(t, pointer(last), pointer(root["items"].([]interface{})[2]))
+	deep := root["d"].(map[string]interface{})["e"].([]interface{})[0].(map[string]interface{})
+	write(`, {"g": 2}]`)
+	require.Equal(t, pointer(deep), pointer(root["d"].(map[string]interface{})["e"].([]interface{})[0]))
+
+	write(`}`)
+	var expected interface{}
+	require.NoError(t, parser.UnmarshalTo(&expected))
+	require.Equal(t, expected, bound)
+}
+
+func TestIncompleteJsonParser_BindDuplicateKeys(t *testing.T) {
+	for _, tt := range []struct {
+		policy   DuplicateKeyPolicy
+		expected map[string]interface{}
+	}{
+		{DuplicateKeysLastWins, map[string]interface{}{"a": float64(3), "b": float64(1)}},
+		{DuplicateKeysFirstWins, map[string]interface{}{"a": float64(1), "b": float64(1)}},
+		{DuplicateKeysCollect, map[string]interface{}{"a": []interface{}{float64(1), float64(2), float64(3)}, "b": float64(1)}},
+	} {
+		parser := NewIncompleteJsonParser(WithDuplicateKeys(tt.policy))
+		var bound map[string]interface{}
+		require.NoError(t, parser.Bind(&bound))
+		for _, chunk := range []string{`{"a": 1, "b"`, `: 1, "a": 2`, `, "a"`, `: 3}`} {
+			_, err := parser.WriteString(chunk)
+			require.NoError(t, err)
+
+			var expected map[string]interface{}
+			require.NoError(t, parser.UnmarshalTo(&expected))
+			require.Equal(t, expected, bound, chunk)
+		}
+		require.Equal(t, tt.expected, bound)
+	}
+}
+
+func TestWithSchema_ObjectMembersAcrossWrites(t *testing.T) {
+	schema := []byte(`{"additionalProperties": {"type": "integer"}}`)
+
+	parser := NewIncompleteJsonParser(WithSchema(schema))
+	for _, chunk := range []string{`{"a": 1, "b"`, `: 2, "c": 3`, `, "d": 4`} {
+		_, err := parser.WriteString(chunk)
+		require.NoError(t, err)
+	}
+	_, err := parser.WriteString(`, "e": "x"`)
+	var schemaErr *SchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	require.Equal(t, "/e", schemaErr.Path.String())
+
+	// A repeated key is checked again when it replaces its member
+	parser = NewIncompleteJsonParser(WithSchema(schema))
+	_, err = parser.WriteString(`{"a": 1, "b": 2, `)
+	require.NoError(t, err)
+	_, err = parser.WriteString(`"a": true`)
+	require.ErrorAs(t, err, &schemaErr)
+	require.Equal(t, "/a", schemaErr.Path.String())
+}
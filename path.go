@@ -0,0 +1,47 @@
+package incompletejson
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Path locates a value inside a document as a sequence of object keys (string)
+// and array indexes (int). The empty path is the root value.
+type Path []interface{}
+
+// String formats the path as a JSON Pointer (RFC 6901), e.g. "/items/0/name"
+func (p Path) String() string {
+	var sb strings.Builder
+	for _, element := range p {
+		sb.WriteByte('/')
+		switch e := element.(type) {
+		case int:
+			sb.WriteString(strconv.Itoa(e))
+		case string:
+			sb.WriteString(pointerEscaper.Replace(e))
+		}
+	}
+	return sb.String()
+}
+
+// append returns a new path with element added, never sharing storage with p
+func (p Path) append(element interface{}) Path {
+	path := make(Path, len(p), len(p)+1)
+	copy(path, p)
+	return append(path, element)
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// equal reports whether p and other locate the same value
+func (p Path) equal(other Path) bool {
+	if len(p) != len(other) {
+		return false
+	}
+	for i := range p {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
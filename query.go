@@ -0,0 +1,245 @@
+package incompletejson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Result is a value addressed by a JSON Pointer or selected by a JSONPath query
+type Result struct {
+	Path Path
+	// Value is the value as GetObjects returns it, including guesses for partial values
+	Value interface{}
+	// Exists reports whether the value has started
+	Exists bool
+	// Complete reports whether the value is final
+	Complete bool
+}
+
+// QueryError is returned for a JSON Pointer or JSONPath query that cannot be parsed
+type QueryError struct {
+	Query  string
+	Offset int // byte offset of the problem in Query
+	Reason string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query %q at offset %d: %s", e.Query, e.Offset, e.Reason)
+}
+
+// Get returns the value at pointer, a JSON Pointer (RFC 6901) such as "/tool_calls/0/arguments/query".
+// Only the addressed value is built, never the whole document. A value that has not started yet
+// is reported with Exists set to false.
+func (p *IncompleteJsonParser) Get(pointer string) (Result, error) {
+	node, ok, err := p.lookup(pointer)
+	if err != nil || !ok {
+		return Result{}, err
+	}
+	return node.result(), nil
+}
+
+// GetAs returns the value at pointer converted to T, together with whether it exists and is complete.
+// The zero value of T is returned while the value has not started. Like UnmarshalTo, an incomplete
+// value that cannot be converted to T yet leaves it at its zero value.
+func GetAs[T any](p *IncompleteJsonParser, pointer string) (T, Result, error) {
+	var value T
+	node, ok, err := p.lookup(pointer)
+	if err != nil || !ok {
+		return value, Result{}, err
+	}
+	result := node.result()
+	if converted, ok := result.Value.(T); ok {
+		return converted, result, nil
+	}
+	err = p.decodeNode(node, &value)
+	return value, result, err
+}
+
+// lookup finds the node at pointer; ok is false if the value has not started
+func (p *IncompleteJsonParser) lookup(pointer string) (node queryNode, ok bool, err error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil || p.scope == nil {
+		return queryNode{}, false, err
+	}
+
+	node = rootNode(p.scope)
+	for _, token := range tokens {
+		switch node.scope.(type) {
+		case *ObjectScope:
+			node, ok = node.member(token)
+		case *ArrayScope:
+			var index int
+			if index, ok = parseIndex(token); ok {
+				node, ok = node.element(index)
+			}
+		default:
+			ok = false
+		}
+		if !ok {
+			return queryNode{}, false, nil
+		}
+	}
+	return node, true, nil
+}
+
+// parsePointer splits a JSON Pointer into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, &QueryError{Query: pointer, Reason: "a JSON Pointer must start with '/'"}
+	}
+
+	var tokens []string
+	offset := 1
+	for _, token := range strings.Split(pointer[1:], "/") {
+		for i := 0; i < len(token); i++ {
+			if token[i] == '~' && (i+1 == len(token) || (token[i+1] != '0' && token[i+1] != '1')) {
+				return nil, &QueryError{Query: pointer, Offset: offset + i, Reason: "'~' must be followed by '0' or '1'"}
+			}
+		}
+		tokens = append(tokens, pointerUnescaper.Replace(token))
+		offset += len(token) + 1
+	}
+	return tokens, nil
+}
+
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// parseIndex parses an array index token, which has no sign and no leading zeros
+func parseIndex(token string) (int, bool) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, false
+	}
+	index := 0
+	for i := 0; i < len(token); i++ {
+		if !isDigit(rune(token[i])) || index > (maxInt-9)/10 {
+			return 0, false
+		}
+		index = index*10 + int(token[i]-'0')
+	}
+	return index, true
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+// queryNode is a value reached by a query
+type queryNode struct {
+	scope Scope
+	path  Path
+	// complete reports whether the value is final, which includes values that
+	// lenient mode accepted unfinished
+	complete bool
+}
+
+func rootNode(scope Scope) queryNode {
+	return queryNode{scope: scope, path: Path{}, complete: scope.IsFinished()}
+}
+
+// child returns the node of a member or element. movedPast reports whether the
+// container has moved past the value, which makes it final.
+func (n queryNode) child(scope Scope, element interface{}, movedPast bool) queryNode {
+	return queryNode{
+		scope:    scope,
+		path:     n.path.append(element),
+		complete: n.complete || movedPast || scope.IsFinished(),
+	}
+}
+
+// member returns the node of the member with key, if the node is an object that has it
+func (n queryNode) member(key string) (queryNode, bool) {
+	object, ok := n.scope.(*ObjectScope)
+	if !ok {
+		return queryNode{}, false
+	}
+	scope := object.memberScope(key)
+	if scope == nil {
+		return queryNode{}, false
+	}
+	return n.child(scope, key, scope != object.valueScope), true
+}
+
+// element returns the node of the element at index, counted from the end when negative
+func (n queryNode) element(index int) (queryNode, bool) {
+	array, ok := n.scope.(*ArrayScope)
+	if !ok {
+		return queryNode{}, false
+	}
+	if index < 0 {
+		index += len(array.array)
+	}
+	if index < 0 || index >= len(array.array) {
+		return queryNode{}, false
+	}
+	element := array.array[index]
+	return n.child(element, index, element != array.pendingScope()), true
+}
+
+// children returns the nodes of the members or elements in document order
+func (n queryNode) children() []queryNode {
+	switch s := n.scope.(type) {
+	case *ObjectScope:
+		children := make([]queryNode, 0, len(s.keys)+1)
+		for _, key := range s.keys {
+			scope := s.memberScope(key)
+			children = append(children, n.child(scope, key, scope != s.valueScope))
+		}
+		if s.valueScope != nil {
+			if key := s.currentKey(); s.members[key] == nil {
+				children = append(children, n.child(s.valueScope, key, false))
+			}
+		}
+		return children
+	case *ArrayScope:
+		pending := s.pendingScope()
+		children := make([]queryNode, 0, len(s.array))
+		for i, element := range s.array {
+			children = append(children, n.child(element, i, element != pending))
+		}
+		return children
+	}
+	return nil
+}
+
+// membersSince returns the nodes of the members an object committed after the cursor,
+// followed by the member being written, so that readers can skip members they have handled
+func (n queryNode) membersSince(c memberCursor) []queryNode {
+	object := n.scope.(*ObjectScope)
+	keys := object.committedSince(c)
+	children := make([]queryNode, 0, len(keys)+1)
+	pending := false
+	for _, key := range keys {
+		scope := object.memberScope(key)
+		pending = pending || scope == object.valueScope
+		children = append(children, n.child(scope, key, scope != object.valueScope))
+	}
+	if object.valueScope != nil && !pending {
+		// The pending member may replace a member committed before the cursor
+		if key := object.currentKey(); object.memberScope(key) == object.valueScope {
+			children = append(children, n.child(object.valueScope, key, false))
+		}
+	}
+	return children
+}
+
+// descendants appends the node and every value inside it to nodes in document order
+func (n queryNode) descendants(nodes []queryNode) []queryNode {
+	nodes = append(nodes, n)
+	for _, child := range n.children() {
+		nodes = child.descendants(nodes)
+	}
+	return nodes
+}
+
+func (n queryNode) result() Result {
+	return Result{Path: n.path, Value: n.scope.GetOrAssume(), Exists: true, Complete: n.complete}
+}
+
+// pendingScope returns the element being written, which the array has not moved past
+func (a *ArrayScope) pendingScope() Scope {
+	if a.state == "value" {
+		return a.scope
+	}
+	return nil
+}
@@ -0,0 +1,661 @@
+package incompletejson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaError is returned when the document violates the schema set with WithSchema.
+// Violations are reported as soon as no continuation of the input could satisfy the schema.
+type SchemaError struct {
+	Path    Path   // path of the offending value
+	Keyword string // schema keyword that failed, e.g. "type" or "maxItems"
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("schema violation (%s): %s", e.Keyword, e.Message)
+	}
+	return fmt.Sprintf("schema violation in %s (%s): %s", e.Path, e.Keyword, e.Message)
+}
+
+// WithSchema validates the document against a JSON Schema on every Write, so that violations
+// are caught while the input is still streaming. A practical subset of draft 2020-12 is
+// supported: type, enum, const, properties, patternProperties, additionalProperties, required,
+// minProperties, maxProperties, prefixItems, items, minItems, maxItems, minLength, maxLength,
+// pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, allOf, anyOf,
+// oneOf, not, and $ref to a location in the same schema. Other keywords are ignored.
+//
+// Violations are returned as *SchemaError and stop the parser. If the schema itself cannot be
+// compiled, Write returns the compile error.
+func WithSchema(schemaBytes []byte) ParserOption {
+	return func(p *IncompleteJsonParser) {
+		p.schema, p.schemaErr = compileSchema(schemaBytes)
+	}
+}
+
+// checkSchema validates the current document against the schema; a violation is fatal
+func (p *IncompleteJsonParser) checkSchema() error {
+	if p.schema == nil || p.scope == nil || p.err != nil {
+		return p.err
+	}
+	if p.schemaValidator.passed == nil {
+		p.schemaValidator = schemaValidator{
+			passed:  make(map[schemaCacheKey]uint64),
+			items:   make(map[schemaCacheKey]int),
+			members: make(map[schemaCacheKey]memberCursor),
+		}
+	}
+	if err := p.schemaValidator.validate(p.schema, rootNode(p.scope)); err != nil {
+		p.err = err
+	}
+	return p.err
+}
+
+// schema is a compiled JSON Schema
+type schema struct {
+	always *bool // the schema is the boolean true or false
+
+	types    []string
+	enum     []interface{}
+	hasEnum  bool
+	constant interface{}
+	hasConst bool
+
+	properties           map[string]*schema
+	patternProperties    []patternSchema
+	additionalProperties *schema
+	required             []string
+	minProperties        int
+	maxProperties        int
+
+	prefixItems []*schema
+	items       *schema
+	minItems    int
+	maxItems    int
+
+	minLength int
+	maxLength int
+	pattern   *regexp.Regexp
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       float64
+
+	allOf []*schema
+	anyOf []*schema
+	oneOf []*schema
+	not   *schema
+	ref   *schema
+}
+
+type patternSchema struct {
+	pattern *regexp.Regexp
+	schema  *schema
+}
+
+// schemaCompiler compiles a schema document, sharing the schema of every location so that $ref can recurse
+type schemaCompiler struct {
+	root      interface{}
+	locations map[string]*schema
+}
+
+func compileSchema(data []byte) (*schema, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var root interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	c := &schemaCompiler{root: root, locations: make(map[string]*schema)}
+	return c.compile("#", root)
+}
+
+// compile compiles the schema at location, a JSON Pointer fragment such as "#/$defs/item"
+func (c *schemaCompiler) compile(location string, value interface{}) (*schema, error) {
+	if s, ok := c.locations[location]; ok {
+		return s, nil
+	}
+	s := &schema{minProperties: -1, maxProperties: -1, minItems: -1, maxItems: -1, minLength: -1, maxLength: -1}
+	c.locations[location] = s
+
+	switch v := value.(type) {
+	case bool:
+		s.always = &v
+		return s, nil
+	case map[string]interface{}:
+		return s, c.compileKeywords(s, location, v)
+	}
+	return nil, fmt.Errorf("invalid schema at %s: expected object or boolean", location)
+}
+
+func (c *schemaCompiler) compileKeywords(s *schema, location string, keywords map[string]interface{}) error {
+	var err error
+	sub := func(keyword string, value interface{}) *schema {
+		if err != nil {
+			return nil
+		}
+		var compiled *schema
+		compiled, err = c.compile(location+"/"+keyword, value)
+		return compiled
+	}
+	subs := func(keyword string, value interface{}) []*schema {
+		list, _ := value.([]interface{})
+		compiled := make([]*schema, 0, len(list))
+		for i, item := range list {
+			compiled = append(compiled, sub(fmt.Sprintf("%s/%d", keyword, i), item))
+		}
+		return compiled
+	}
+	count := func(value interface{}) int {
+		if number, ok := value.(float64); ok && number >= 0 {
+			return int(number)
+		}
+		return -1
+	}
+	number := func(value interface{}) *float64 {
+		if number, ok := value.(float64); ok {
+			return &number
+		}
+		return nil
+	}
+
+	for keyword, value := range keywords {
+		switch keyword {
+		case "type":
+			switch v := value.(type) {
+			case string:
+				s.types = []string{v}
+			case []interface{}:
+				for _, t := range v {
+					if name, ok := t.(string); ok {
+						s.types = append(s.types, name)
+					}
+				}
+			}
+		case "enum":
+			s.enum, _ = value.([]interface{})
+			s.hasEnum = true
+		case "const":
+			s.constant, s.hasConst = value, true
+		case "properties":
+			properties, _ := value.(map[string]interface{})
+			s.properties = make(map[string]*schema, len(properties))
+			for name, property := range properties {
+				s.properties[name] = sub("properties/"+pointerEscaper.Replace(name), property)
+			}
+		case "patternProperties":
+			properties, _ := value.(map[string]interface{})
+			for expr, property := range properties {
+				re, reErr := regexp.Compile(expr)
+				if reErr != nil {
+					return fmt.Errorf("invalid schema at %s/patternProperties: %w", location, reErr)
+				}
+				s.patternProperties = append(s.patternProperties, patternSchema{re, sub("patternProperties/"+pointerEscaper.Replace(expr), property)})
+			}
+			sort.Slice(s.patternProperties, func(i, j int) bool {
+				return s.patternProperties[i].pattern.String() < s.patternProperties[j].pattern.String()
+			})
+		case "additionalProperties":
+			s.additionalProperties = sub(keyword, value)
+		case "required":
+			list, _ := value.([]interface{})
+			for _, name := range list {
+				if name, ok := name.(string); ok {
+					s.required = append(s.required, name)
+				}
+			}
+		case "minProperties":
+			s.minProperties = count(value)
+		case "maxProperties":
+			s.maxProperties = count(value)
+		case "prefixItems":
+			s.prefixItems = subs(keyword, value)
+		case "items":
+			if _, ok := value.([]interface{}); ok {
+				// Draft 2019-09 and earlier spell prefixItems as an array of items
+				s.prefixItems = subs(keyword, value)
+			} else {
+				s.items = sub(keyword, value)
+			}
+		case "minItems":
+			s.minItems = count(value)
+		case "maxItems":
+			s.maxItems = count(value)
+		case "minLength":
+			s.minLength = count(value)
+		case "maxLength":
+			s.maxLength = count(value)
+		case "pattern":
+			expr, _ := value.(string)
+			re, reErr := regexp.Compile(expr)
+			if reErr != nil {
+				return fmt.Errorf("invalid schema at %s/pattern: %w", location, reErr)
+			}
+			s.pattern = re
+		case "minimum":
+			s.minimum = number(value)
+		case "maximum":
+			s.maximum = number(value)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum = number(value)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum = number(value)
+		case "multipleOf":
+			if m := number(value); m != nil && *m > 0 {
+				s.multipleOf = *m
+			}
+		case "allOf":
+			s.allOf = subs(keyword, value)
+		case "anyOf":
+			s.anyOf = subs(keyword, value)
+		case "oneOf":
+			s.oneOf = subs(keyword, value)
+		case "not":
+			s.not = sub(keyword, value)
+		case "$ref":
+			ref, _ := value.(string)
+			target, ok := c.resolve(ref)
+			if !ok {
+				return fmt.Errorf("invalid schema at %s: unsupported $ref %q", location, ref)
+			}
+			if err == nil {
+				s.ref, err = c.compile(ref, target)
+			}
+		}
+	}
+	return err
+}
+
+// resolve finds the target of a $ref within the schema document
+func (c *schemaCompiler) resolve(ref string) (interface{}, bool) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, false
+	}
+	tokens, err := parsePointer(ref[1:])
+	if err != nil {
+		return nil, false
+	}
+	value := c.root
+	for _, token := range tokens {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if value, err = v[token], nil; value == nil {
+				return nil, false
+			}
+		case []interface{}:
+			index, ok := parseIndex(token)
+			if !ok || index >= len(v) {
+				return nil, false
+			}
+			value = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// schemaCacheKey identifies a value validated against a schema
+type schemaCacheKey struct {
+	scope  Scope
+	schema *schema
+}
+
+// schemaValidator checks a partial document against a schema. Only definite violations are
+// reported: a check that a later part of the input could still satisfy waits until the value
+// is complete. Complete values that passed are remembered, so each is only checked once.
+type schemaValidator struct {
+	passed  map[schemaCacheKey]uint64       // complete values that passed a schema, with their version
+	items   map[schemaCacheKey]int          // number of leading array elements that are complete and passed
+	members map[schemaCacheKey]memberCursor // object members that are committed and passed
+}
+
+func (v schemaValidator) validate(s *schema, node queryNode) *SchemaError {
+	if s.always != nil {
+		if !*s.always {
+			return schemaViolation(node, "false", "no value is allowed")
+		}
+		return nil
+	}
+	// A complete value is checked again only if DuplicateKeysCollect added to it
+	key := schemaCacheKey{node.scope, s}
+	version := baseScope(node.scope).version
+	if passed, ok := v.passed[key]; ok && passed == version {
+		return nil
+	}
+
+	if err := v.validateValue(s, node); err != nil {
+		return err
+	}
+	if err := v.validateApplicators(s, node); err != nil {
+		return err
+	}
+	if node.complete {
+		v.passed[key] = version
+	}
+	return nil
+}
+
+func (v schemaValidator) validateValue(s *schema, node queryNode) *SchemaError {
+	kind := schemaKind(node.scope)
+	if len(s.types) > 0 && !s.matchesType(kind, node) {
+		return schemaViolation(node, "type", fmt.Sprintf("expected %s, got %s", strings.Join(s.types, " or "), kind))
+	}
+	if s.hasConst {
+		if err := checkEnum(node, "const", []interface{}{s.constant}); err != nil {
+			return err
+		}
+	}
+	if s.hasEnum {
+		if err := checkEnum(node, "enum", s.enum); err != nil {
+			return err
+		}
+	}
+
+	switch scope := node.scope.(type) {
+	case *ObjectScope:
+		return v.validateObject(s, node, scope)
+	case *ArrayScope:
+		return v.validateArray(s, node, scope)
+	case *LiteralScope:
+		switch kind {
+		case "string":
+			return validateString(s, node, scope.text())
+		case "number":
+			if node.complete {
+				return validateNumber(s, node, filterValue(scope).(float64))
+			}
+		}
+	}
+	return nil
+}
+
+func (v schemaValidator) validateApplicators(s *schema, node queryNode) *SchemaError {
+	if s.ref != nil {
+		if err := v.validate(s.ref, node); err != nil {
+			return err
+		}
+	}
+	for _, sub := range s.allOf {
+		if err := v.validate(sub, node); err != nil {
+			return err
+		}
+	}
+	if len(s.anyOf) > 0 {
+		matched := false
+		for _, sub := range s.anyOf {
+			if v.validate(sub, node) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return schemaViolation(node, "anyOf", "value does not match any of the schemas")
+		}
+	}
+	if len(s.oneOf) > 0 {
+		matched := 0
+		for _, sub := range s.oneOf {
+			if v.validate(sub, node) == nil {
+				matched++
+			}
+		}
+		if matched == 0 {
+			return schemaViolation(node, "oneOf", "value does not match any of the schemas")
+		}
+		if matched > 1 && node.complete {
+			return schemaViolation(node, "oneOf", "value matches more than one schema")
+		}
+	}
+	if s.not != nil && node.complete && v.validate(s.not, node) == nil {
+		return schemaViolation(node, "not", "value matches a schema it must not match")
+	}
+	return nil
+}
+
+func (v schemaValidator) validateObject(s *schema, node queryNode, object *ObjectScope) *SchemaError {
+	// Members committed before the last check passed and are not checked again
+	cacheKey := schemaCacheKey{node.scope, s}
+	keys := object.committedSince(v.members[cacheKey])
+	properties := len(object.keys)
+	if object.keyScope != nil && object.keyScope.IsFinished() {
+		if key := object.currentKey(); object.members[key] == nil {
+			keys = append(keys[:len(keys):len(keys)], key)
+			properties++
+		}
+	}
+
+	if s.maxProperties >= 0 && properties > s.maxProperties {
+		return schemaViolation(node, "maxProperties", fmt.Sprintf("object has more than %d properties", s.maxProperties))
+	}
+	if s.additionalProperties != nil && s.additionalProperties.always != nil && !*s.additionalProperties.always {
+		// Unknown keys are reported as soon as they are complete, before their value starts
+		for _, key := range keys {
+			if !s.knownProperty(key) {
+				return &SchemaError{Path: node.path.append(key), Keyword: "additionalProperties", Message: fmt.Sprintf("property %q is not allowed", key)}
+			}
+		}
+	}
+
+	for _, child := range node.membersSince(v.members[cacheKey]) {
+		key := child.path[len(child.path)-1].(string)
+		known := false
+		if property, ok := s.properties[key]; ok {
+			known = true
+			if err := v.validate(property, child); err != nil {
+				return err
+			}
+		}
+		for _, pattern := range s.patternProperties {
+			if pattern.pattern.MatchString(key) {
+				known = true
+				if err := v.validate(pattern.schema, child); err != nil {
+					return err
+				}
+			}
+		}
+		if !known && s.additionalProperties != nil {
+			if err := v.validate(s.additionalProperties, child); err != nil {
+				return err
+			}
+		}
+	}
+	v.members[cacheKey] = object.cursor()
+
+	if node.complete {
+		for _, name := range s.required {
+			if object.members[name] == nil {
+				return schemaViolation(node, "required", fmt.Sprintf("missing required property %q", name))
+			}
+		}
+		if s.minProperties >= 0 && len(object.keys) < s.minProperties {
+			return schemaViolation(node, "minProperties", fmt.Sprintf("object has fewer than %d properties", s.minProperties))
+		}
+	}
+	return nil
+}
+
+// knownProperty reports whether key is matched by properties or patternProperties
+func (s *schema) knownProperty(key string) bool {
+	if _, ok := s.properties[key]; ok {
+		return true
+	}
+	for _, pattern := range s.patternProperties {
+		if pattern.pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v schemaValidator) validateArray(s *schema, node queryNode, array *ArrayScope) *SchemaError {
+	if s.maxItems >= 0 && len(array.array) > s.maxItems {
+		return schemaViolation(node, "maxItems", fmt.Sprintf("array has more than %d items", s.maxItems))
+	}
+
+	// Leading elements that are complete and passed are not checked again
+	key := schemaCacheKey{node.scope, s}
+	checked := v.items[key]
+	for i := checked; i < len(array.array); i++ {
+		child, _ := node.element(i)
+		item := s.items
+		if i < len(s.prefixItems) {
+			item = s.prefixItems[i]
+		}
+		if item != nil {
+			if err := v.validate(item, child); err != nil {
+				return err
+			}
+		}
+		if child.complete && checked == i {
+			checked++
+		}
+	}
+	v.items[key] = checked
+
+	if node.complete && s.minItems >= 0 && len(array.array) < s.minItems {
+		return schemaViolation(node, "minItems", fmt.Sprintf("array has fewer than %d items", s.minItems))
+	}
+	return nil
+}
+
+func validateString(s *schema, node queryNode, text string) *SchemaError {
+	// The text only grows, so a string that is too long already fails
+	length := utf8.RuneCountInString(text)
+	if s.maxLength >= 0 && length > s.maxLength {
+		return schemaViolation(node, "maxLength", fmt.Sprintf("string is longer than %d characters", s.maxLength))
+	}
+	if !node.complete {
+		return nil
+	}
+	if s.minLength >= 0 && length < s.minLength {
+		return schemaViolation(node, "minLength", fmt.Sprintf("string is shorter than %d characters", s.minLength))
+	}
+	if s.pattern != nil && !s.pattern.MatchString(text) {
+		return schemaViolation(node, "pattern", fmt.Sprintf("string does not match %q", s.pattern))
+	}
+	return nil
+}
+
+func validateNumber(s *schema, node queryNode, number float64) *SchemaError {
+	switch {
+	case s.minimum != nil && number < *s.minimum:
+		return schemaViolation(node, "minimum", fmt.Sprintf("%v is less than %v", number, *s.minimum))
+	case s.maximum != nil && number > *s.maximum:
+		return schemaViolation(node, "maximum", fmt.Sprintf("%v is greater than %v", number, *s.maximum))
+	case s.exclusiveMinimum != nil && number <= *s.exclusiveMinimum:
+		return schemaViolation(node, "exclusiveMinimum", fmt.Sprintf("%v is not greater than %v", number, *s.exclusiveMinimum))
+	case s.exclusiveMaximum != nil && number >= *s.exclusiveMaximum:
+		return schemaViolation(node, "exclusiveMaximum", fmt.Sprintf("%v is not less than %v", number, *s.exclusiveMaximum))
+	}
+	if s.multipleOf > 0 {
+		quotient := number / s.multipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			return schemaViolation(node, "multipleOf", fmt.Sprintf("%v is not a multiple of %v", number, s.multipleOf))
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether a value of kind can satisfy the type keyword
+func (s *schema) matchesType(kind string, node queryNode) bool {
+	for _, t := range s.types {
+		switch {
+		case t == kind:
+			return true
+		case t == "integer" && kind == "number":
+			// Whether the number is whole is only known once it is complete
+			if !node.complete {
+				return true
+			}
+			number := filterValue(node.scope).(float64)
+			if number == math.Trunc(number) && !math.IsInf(number, 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkEnum checks a value against a list of allowed values. A partial string fails as soon
+// as it is no prefix of any allowed string; other values are compared once they are complete.
+func checkEnum(node queryNode, keyword string, allowed []interface{}) *SchemaError {
+	if !node.complete {
+		literal, ok := node.scope.(*LiteralScope)
+		if !ok || schemaKind(literal) != "string" {
+			return nil
+		}
+		text := literal.text()
+		for _, value := range allowed {
+			if value, ok := value.(string); ok && strings.HasPrefix(value, text) {
+				return nil
+			}
+		}
+		return schemaViolation(node, keyword, fmt.Sprintf("string %q cannot match any allowed value", text))
+	}
+
+	value := schemaValue(node)
+	for _, candidate := range allowed {
+		if reflect.DeepEqual(value, candidate) {
+			return nil
+		}
+	}
+	if keyword == "const" {
+		return schemaViolation(node, keyword, "value does not equal the constant")
+	}
+	return schemaViolation(node, keyword, "value is not one of the allowed values")
+}
+
+// schemaValue returns a complete value the way encoding/json decodes it, for comparisons with schema values
+func schemaValue(node queryNode) interface{} {
+	switch node.scope.(type) {
+	case *ObjectScope:
+		object := make(map[string]interface{})
+		for _, child := range node.children() {
+			object[child.path[len(child.path)-1].(string)] = schemaValue(child)
+		}
+		return object
+	case *ArrayScope:
+		children := node.children()
+		array := make([]interface{}, 0, len(children))
+		for _, child := range children {
+			array = append(array, schemaValue(child))
+		}
+		return array
+	}
+	return filterValue(node.scope)
+}
+
+// schemaKind returns the JSON Schema type of a value, which is known as soon as the value starts
+func schemaKind(scope Scope) string {
+	switch s := scope.(type) {
+	case *ObjectScope:
+		return "object"
+	case *ArrayScope:
+		return "array"
+	case *LiteralScope:
+		switch s.nodeKind() {
+		case NodeString:
+			return "string"
+		case NodeNumber:
+			return "number"
+		case NodeBool:
+			return "boolean"
+		}
+	}
+	return "null"
+}
+
+func schemaViolation(node queryNode, keyword string, message string) *SchemaError {
+	return &SchemaError{Path: node.path, Keyword: keyword, Message: message}
+}
@@ -12,10 +12,54 @@ type Scope interface {
 	SetAllowUnescapedNewlines(allow bool)
 }
 
+// scopeContext holds the parser settings and state shared by every scope of a document
+type scopeContext struct {
+	numberMode         NumberMode
+	numberDecoder      NumberDecoder
+	orderedObjects     bool
+	immutableSnapshots bool
+	duplicateKeys      DuplicateKeyPolicy
+	strict             bool
+
+	// Dialect extensions
+	comments       bool
+	singleQuotes   bool
+	unquotedKeys   bool
+	trailingCommas bool
+	json5          bool
+	pythonLiterals bool
+
+	onValueComplete func(path Path, value interface{})
+	observers       []func(Event)
+	subscriptions   []*Subscription
+
+	// err is a fatal error raised by a scope; it stops parsing even where the
+	// enclosing scopes would tolerate the rejected character
+	err error
+
+	// The innermost scope that rejected the current character and what it expected instead
+	rejected     bool
+	expected     string
+	rejectedPath Path
+}
+
+// defaultScopeContext is used by scopes created outside of a parser
+var defaultScopeContext = scopeContext{}
+
 // BaseScope provides common functionality for all scopes
 type BaseScope struct {
 	finish                 bool
 	allowUnescapedNewlines bool
+	context                *scopeContext
+	path                   Path
+	// version is bumped every time the scope is written to; a snapshot taken at
+	// the same version is still up to date
+	version       uint64
+	cachedVersion uint64
+	cached        bool
+	// node is the node of the value once it is complete, built at nodeVersion
+	node        *Node
+	nodeVersion uint64
 }
 
 func (s *BaseScope) IsFinished() bool {
@@ -26,7 +70,156 @@ func (s *BaseScope) SetAllowUnescapedNewlines(allow bool) {
 	s.allowUnescapedNewlines = allow
 }
 
+// ctx returns the parser settings and state of the scope
+func (s *BaseScope) ctx() *scopeContext {
+	if s.context == nil {
+		return &defaultScopeContext
+	}
+	return s.context
+}
+
+// fail records a fatal error for the parser to return
+func (s *BaseScope) fail(err error) bool {
+	if s.context != nil && s.context.err == nil {
+		s.context.err = err
+	}
+	return false
+}
+
+// reject records what the scope expected instead of the current character and returns false.
+// The innermost scope to reject a character describes the error.
+func (s *BaseScope) reject(expected string) bool {
+	if s.context != nil && !s.context.rejected {
+		s.context.rejected = true
+		s.context.expected = expected
+		s.context.rejectedPath = s.path
+	}
+	return false
+}
+
+// newChildScope creates the scope for a value starting with letter, sharing
+// this scope's settings. element is the key or index of the value in this scope.
+func (s *BaseScope) newChildScope(letter rune, element interface{}) Scope {
+	return newScope(letter, s.allowUnescapedNewlines, s.context, s.path.append(element))
+}
+
+// newKeyScope creates the scope for an object key, sharing this scope's settings
+func (s *BaseScope) newKeyScope() *LiteralScope {
+	key := NewLiteralScope()
+	key.allowUnescapedNewlines = s.allowUnescapedNewlines
+	key.context = s.context
+	key.path = s.path
+	key.key = true
+	return key
+}
+
+// newScope creates the scope for a value starting with letter
+func newScope(letter rune, allowUnescapedNewlines bool, context *scopeContext, path Path) Scope {
+	var scope Scope
+	var base *BaseScope
+	switch {
+	case letter == '{':
+		object := NewObjectScope()
+		scope, base = object, &object.BaseScope
+	case letter == '[' || (letter == '(' && context != nil && context.pythonLiterals):
+		// Python tuples are parsed as arrays
+		array := NewArrayScope()
+		scope, base = array, &array.BaseScope
+	default:
+		literal := NewLiteralScope()
+		scope, base = literal, &literal.BaseScope
+	}
+	base.allowUnescapedNewlines = allowUnescapedNewlines
+	base.context = context
+	base.path = path
+	return scope
+}
+
+// touch marks the scope as possibly changed since the last snapshot
+func (s *BaseScope) touch() {
+	s.version++
+}
+
+// isCached reports whether the last snapshot still reflects the scope
+func (s *BaseScope) isCached() bool {
+	return s.cached && s.cachedVersion == s.version
+}
+
+// markCached records that a snapshot has been taken at the current version
+func (s *BaseScope) markCached() {
+	s.cached = true
+	s.cachedVersion = s.version
+}
+
+// isWhitespace checks if a character is whitespace between tokens
+func (s *BaseScope) isWhitespace(r rune) bool {
+	return s.ctx().isWhitespace(r)
+}
+
+// isWhitespace checks if a character is whitespace between tokens in the configured dialect.
+// Strict mode only accepts the four whitespace characters of RFC 8259.
+func (c *scopeContext) isWhitespace(r rune) bool {
+	switch {
+	case c.json5:
+		return isWhitespace(r) || r == '\uFEFF'
+	case c.strict:
+		return isJSONWhitespace(r)
+	}
+	return isWhitespace(r)
+}
+
+// complete reports a value that is final, either finished or accepted unfinished by its container
+func (c *scopeContext) complete(scope Scope) {
+	c.end(scope)
+	if c.onValueComplete != nil {
+		c.onValueComplete(baseScope(scope).path, scope.GetOrAssume())
+	}
+}
+
+// keywords returns the bare words accepted as values in the configured dialect
+func (c *scopeContext) keywords() []keyword {
+	switch {
+	case c.json5 && c.pythonLiterals:
+		return json5PythonKeywords
+	case c.json5:
+		return json5Keywords
+	case c.pythonLiterals:
+		return jsonPythonKeywords
+	}
+	return jsonKeywords
+}
+
+var (
+	jsonPythonKeywords  = append(jsonKeywords[:len(jsonKeywords):len(jsonKeywords)], pythonKeywords...)
+	json5PythonKeywords = append(json5Keywords[:len(json5Keywords):len(json5Keywords)], pythonKeywords...)
+)
+
+// activeLiteral returns the innermost literal that is still being written, if any
+func activeLiteral(scope Scope) *LiteralScope {
+	for scope != nil && !scope.IsFinished() {
+		switch s := scope.(type) {
+		case *ObjectScope:
+			if s.keyScope != nil && !s.keyScope.IsFinished() {
+				return s.keyScope
+			}
+			scope = s.valueScope
+		case *ArrayScope:
+			scope = s.scope
+		case *LiteralScope:
+			return s
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
 // isWhitespace checks if a character is whitespace
 func isWhitespace(r rune) bool {
 	return unicode.IsSpace(r)
 }
+
+// isJSONWhitespace checks if a character is whitespace as defined by RFC 8259
+func isJSONWhitespace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
@@ -0,0 +1,106 @@
+package incompletejson
+
+// Subscription receives the text of a string value as it is decoded.
+// Escape sequences are resolved before the text is delivered, so an escape
+// split across Write calls arrives as the character it encodes.
+type Subscription struct {
+	path     Path
+	text     []byte
+	complete bool
+	// skip is the length of the text already buffered that the next fragment repeats
+	skip    int
+	context *scopeContext // nil once the subscription no longer receives text
+}
+
+// Subscribe returns a subscription to the string value at path. Text decoded
+// before the call is included in the first delta. Once the string closes, the
+// subscription is complete and later values at the same path are ignored.
+// A subscription stops receiving text when it completes or is closed.
+func (p *IncompleteJsonParser) Subscribe(path Path) *Subscription {
+	s := &Subscription{path: append(Path(nil), path...)}
+	if literal, ok := lookupScope(p.scope, path).(*LiteralScope); ok && literal.kind == literalString {
+		text := literal.text()
+		s.text = append(s.text, text...)
+		if literal.IsFinished() {
+			s.complete = true
+			return s
+		}
+		// Text past emitted is reported again by the next fragment
+		s.skip = len(text) - literal.emitted
+	}
+	s.context = &p.scopeContext
+	p.scopeContext.subscriptions = append(p.scopeContext.subscriptions, s)
+	return s
+}
+
+// observe collects the string fragments reported at the subscribed path
+func (s *Subscription) observe(event Event) {
+	if s.complete || event.Kind != EventString || !event.Path.equal(s.path) {
+		return
+	}
+	fragment := event.Value.(string)
+	skipped := min(s.skip, len(fragment))
+	s.skip -= skipped
+	s.text = append(s.text, fragment[skipped:]...)
+	s.complete = event.Complete
+}
+
+// Close stops the subscription from receiving text. Text already decoded is still returned by Delta.
+func (s *Subscription) Close() {
+	if s.context != nil {
+		s.context.unsubscribe(s)
+	}
+}
+
+// Delta returns the text decoded since the previous call, or "" if there is none
+func (s *Subscription) Delta() string {
+	delta := string(s.text)
+	s.text = s.text[:0]
+	return delta
+}
+
+// Complete reports whether the string has closed
+func (s *Subscription) Complete() bool {
+	return s.complete
+}
+
+// lookupScope returns the scope of the value at path, or nil if it has not started
+func lookupScope(scope Scope, path Path) Scope {
+	for _, element := range path {
+		switch s := scope.(type) {
+		case *ObjectScope:
+			key, ok := element.(string)
+			if !ok {
+				return nil
+			}
+			scope = s.memberScope(key)
+		case *ArrayScope:
+			index, ok := element.(int)
+			if !ok || index < 0 || index >= len(s.array) {
+				return nil
+			}
+			scope = s.array[index]
+		default:
+			return nil
+		}
+		if scope == nil {
+			return nil
+		}
+	}
+	return scope
+}
+
+// memberScope returns the scope of the member value with key. A repeated key
+// being parsed replaces the committed value only under DuplicateKeysLastWins.
+func (o *ObjectScope) memberScope(key string) Scope {
+	member, committed := o.members[key]
+	if o.valueScope != nil && o.currentKey() == key {
+		if !committed || o.ctx().duplicateKeys == DuplicateKeysLastWins {
+			return o.valueScope
+		}
+	}
+	if !committed {
+		return nil
+	}
+	return member
+}